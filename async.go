@@ -0,0 +1,89 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// OverflowPolicy is the behavior of an asynchronous hook when all its
+// senders are busy.
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drops the event.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock waits for a sender to be available, for at most the
+	// given timeout, and drops the event on expiry.
+	OverflowBlock
+	// OverflowSync sends the event synchronously on the caller, as a
+	// synchronous hook would, waiting for at most hook.Timeout.
+	OverflowSync
+)
+
+// String returns the name of the policy, as accepted in Config.Overflow.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDrop:
+		return "drop"
+	case OverflowBlock:
+		return "block"
+	case OverflowSync:
+		return "sync"
+	default:
+		return "OverflowPolicy(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// asyncLimit caps the number of events an asynchronous hook sends at once.
+type asyncLimit struct {
+	slots        chan struct{}
+	policy       OverflowPolicy
+	blockTimeout time.Duration
+}
+
+// SetAsyncLimit caps the number of events an asynchronous hook sends at once
+// to max, and sets what happens to the events logged beyond it. blockTimeout
+// is only used by OverflowBlock. Dropped events are counted in the
+// DroppedByOverflow stat.
+func (hook *SentryHook) SetAsyncLimit(max int, policy OverflowPolicy, blockTimeout time.Duration) error {
+	if max <= 0 {
+		return errors.New("async limit must be positive")
+	}
+	hook.asyncLimit = &asyncLimit{
+		slots:        make(chan struct{}, max),
+		policy:       policy,
+		blockTimeout: blockTimeout,
+	}
+	return nil
+}
+
+// acquire reserves a sender. It reports whether one was reserved, and if
+// not, whether the event should be sent synchronously.
+func (l *asyncLimit) acquire() (acquired, sync bool) {
+	select {
+	case l.slots <- struct{}{}:
+		return true, false
+	default:
+	}
+
+	switch l.policy {
+	case OverflowBlock:
+		timer := time.NewTimer(l.blockTimeout)
+		defer timer.Stop()
+		select {
+		case l.slots <- struct{}{}:
+			return true, false
+		case <-timer.C:
+			return false, false
+		}
+	case OverflowSync:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func (l *asyncLimit) release() {
+	<-l.slots
+}
@@ -68,3 +68,53 @@ func TestParallelLogging(t *testing.T) {
 		}
 	})
 }
+
+func TestSetAsyncLimit(t *testing.T) {
+	release := make(chan struct{})
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		<-release
+	})
+	defer s.Close()
+	defer close(release)
+
+	tests := []struct {
+		policy          OverflowPolicy
+		expectedDropped uint64
+		expectedErr     bool
+	}{
+		{OverflowDrop, 1, false},
+		{OverflowBlock, 1, false},
+		{OverflowSync, 0, true},
+	}
+
+	for _, tt := range tests {
+		hook, err := NewAsyncSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		hook.Timeout = 20 * time.Millisecond
+		if err := hook.SetAsyncLimit(1, tt.policy, 20*time.Millisecond); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		entry := &logrus.Entry{Data: logrus.Fields{}, Level: logrus.ErrorLevel, Message: message}
+		if err := hook.Fire(entry); err != nil {
+			t.Errorf("policy %d: first event should be sent asynchronously: %v", tt.policy, err)
+		}
+		err = hook.Fire(entry)
+		if (err != nil) != tt.expectedErr {
+			t.Errorf("policy %d: unexpected error %v", tt.policy, err)
+		}
+		if dropped := hook.Stats().DroppedByOverflow; dropped != tt.expectedDropped {
+			t.Errorf("policy %d: expected %d dropped events, got %d", tt.policy, tt.expectedDropped, dropped)
+		}
+	}
+
+	hook, _ := NewAsyncSentryHook(dsn, nil)
+	if hook.SetAsyncLimit(0, OverflowDrop, 0) == nil {
+		t.Error("zero limit should be an error")
+	}
+}
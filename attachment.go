@@ -0,0 +1,92 @@
+package logrus_sentry
+
+import (
+	"net/http"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// fieldAttachments is the field holding the attachments of an entry.
+const fieldAttachments = "sentry_attachments"
+
+// Attachment is a file sent along with an event, e.g. a request body, a dump
+// or a configuration snapshot, which is too large to be read as extra data.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Payload     []byte
+}
+
+// AddAttachmentExtractor adds a function returning the attachments of an
+// entry, in addition to those of the sentry_attachments field, which holds
+// an Attachment or a []Attachment.
+//
+// Events with attachments are sent in an envelope. They are not spooled
+// with their attachments.
+func (hook *SentryHook) AddAttachmentExtractor(fn func(entry *logrus.Entry) []Attachment) {
+	hook.attachmentExtractors = append(hook.attachmentExtractors, fn)
+}
+
+func (d *dataField) getAttachments() ([]Attachment, bool) {
+	switch attachments := d.data[fieldAttachments].(type) {
+	case []Attachment:
+		d.omitList[fieldAttachments] = struct{}{}
+		return attachments, true
+	case Attachment:
+		d.omitList[fieldAttachments] = struct{}{}
+		return []Attachment{attachments}, true
+	case *Attachment:
+		if attachments != nil {
+			d.omitList[fieldAttachments] = struct{}{}
+			return []Attachment{*attachments}, true
+		}
+	}
+	return nil, false
+}
+
+// entryAttachments returns the attachments of the field and extractors.
+func (hook *SentryHook) entryAttachments(df *dataField, entry *logrus.Entry) []Attachment {
+	attachments, _ := df.getAttachments()
+	for _, fn := range hook.attachmentExtractors {
+		attachments = append(attachments, fn(entry)...)
+	}
+	return attachments
+}
+
+// setAttachments keeps the attachments of packet until it is sent.
+func (hook *SentryHook) setAttachments(packet *raven.Packet, attachments []Attachment) {
+	if packet.EventID == "" {
+		packet.EventID = newEventID()
+	}
+	hook.attachments.Store(packet.EventID, attachments)
+}
+
+// takeAttachments returns and forgets the attachments of packet.
+func (hook *SentryHook) takeAttachments(packet *raven.Packet) []Attachment {
+	attachments, ok := hook.attachments.Load(packet.EventID)
+	if !ok {
+		return nil
+	}
+	hook.attachments.Delete(packet.EventID)
+	return attachments.([]Attachment)
+}
+
+// attachmentTransport sends the packets which have attachments in an
+// envelope, and the others with its Transport.
+type attachmentTransport struct {
+	raven.Transport
+	hook *SentryHook
+}
+
+func (t *attachmentTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	attachments := t.hook.takeAttachments(packet)
+	if len(attachments) == 0 || url == "" {
+		return t.Transport.Send(url, authHeader, packet)
+	}
+	httpClient := http.DefaultClient
+	if ht, ok := t.Transport.(*raven.HTTPTransport); ok && ht.Client != nil {
+		httpClient = ht.Client
+	}
+	return sendEnvelope(httpClient, url, authHeader, packet, attachments)
+}
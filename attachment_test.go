@@ -0,0 +1,93 @@
+package logrus_sentry
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachments(t *testing.T) {
+	a := assert.New(t)
+
+	requests := make(chan *http.Request, 2)
+	bodies := make(chan []byte, 2)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		b, _ := ioutil.ReadAll(req.Body)
+		requests <- req
+		bodies <- b
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = time.Second
+	hook.AddAttachmentExtractor(func(entry *logrus.Entry) []Attachment {
+		if body, ok := entry.Data["response_body"].(string); ok {
+			return []Attachment{{Filename: "response.json", ContentType: "application/json", Payload: []byte(body)}}
+		}
+		return nil
+	})
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.WithFields(logrus.Fields{
+		"sentry_attachments": Attachment{Filename: "dump.txt", Payload: []byte("line 1\nline 2")},
+		"response_body":      `{"error":"internal"}`,
+	}).Error(message)
+
+	req := <-requests
+	a.True(strings.HasSuffix(req.URL.Path, "/envelope/"), "events with attachments should be sent in an envelope")
+	r := bufio.NewReader(strings.NewReader(string(<-bodies)))
+
+	var header map[string]interface{}
+	a.NoError(json.Unmarshal(readEnvelopeLine(r), &header))
+	a.NotEmpty(header["event_id"])
+
+	items := map[string]string{}
+	for {
+		line := readEnvelopeLine(r)
+		if line == nil {
+			break
+		}
+		var itemHeader struct {
+			Type     string `json:"type"`
+			Length   int    `json:"length"`
+			Filename string `json:"filename"`
+		}
+		a.NoError(json.Unmarshal(line, &itemHeader))
+		payload := make([]byte, itemHeader.Length+1)
+		io.ReadFull(r, payload)
+		items[itemHeader.Type+":"+itemHeader.Filename] = string(payload[:itemHeader.Length])
+	}
+
+	var event map[string]interface{}
+	a.NoError(json.Unmarshal([]byte(items["event:"]), &event))
+	a.Equal(message, event["message"])
+	a.NotContains(event["extra"], "sentry_attachments", "attachments should not be sent as extra")
+	a.Equal("line 1\nline 2", items["attachment:dump.txt"])
+	a.Equal(`{"error":"internal"}`, items["attachment:response.json"])
+
+	logger.Error(message)
+	req = <-requests
+	<-bodies
+	a.True(strings.HasSuffix(req.URL.Path, "/store/"), "events without attachments should be sent as before")
+}
+
+func readEnvelopeLine(r *bufio.Reader) []byte {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil
+	}
+	return line[:len(line)-1]
+}
@@ -0,0 +1,287 @@
+package logrus_sentry
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchSize     = 100
+	defaultBatchInterval = time.Second
+)
+
+// BatchConfig configures the batching of outgoing events.
+type BatchConfig struct {
+	// Size is the number of events which triggers the sending of a batch.
+	// Defaults to 100.
+	Size int
+	// Interval is the maximum time an event waits for its batch to be sent.
+	// Defaults to 1 second.
+	Interval time.Duration
+	// OnError is called with the events of a batch which failed to be sent,
+	// as the errors can not be returned by the hook.
+	OnError func(packet *raven.Packet, err error)
+}
+
+// batchTransport accumulates packets and sends them as a batch of envelope
+// requests over a reused connection.
+type batchTransport struct {
+	hook       *SentryHook
+	config     BatchConfig
+	httpClient *http.Client
+	// compat strips the parts a legacy server rejects, if the legacy
+	// compatibility was enabled before the batching
+	compat *compatTransport
+
+	mu      sync.Mutex // guards pending, timer and finalizers
+	pending []batchedPacket
+	timer   *time.Timer
+	// finalizers are the finalizers of the packets waiting for their batch
+	finalizers map[*raven.Packet]Finalizers
+
+	sendMu sync.Mutex // serializes batches, keeping the events order
+}
+
+type batchedPacket struct {
+	url        string
+	authHeader string
+	packet     *raven.Packet
+}
+
+// EnableBatching makes the hook send events in batches of up to cfg.Size
+// events, or every cfg.Interval, instead of one request per event.
+//
+// Sentry accepts a single event per envelope, so a batch is sent as
+// back-to-back envelope requests over one keep-alive connection. Since
+// events are sent after Fire returns, Fire sees an event added to a batch as
+// sent, but the finalizers are called once its batch is sent, with the
+// outcome of its send, except for the split events. The send errors are
+// reported to cfg.OnError and to the error handlers of the hook, with an
+// entry rebuilt from the event, and retryable failures are spooled if the
+// spool is enabled. Flush sends the pending batches.
+func (hook *SentryHook) EnableBatching(cfg BatchConfig) {
+	if cfg.Size <= 0 {
+		cfg.Size = defaultBatchSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultBatchInterval
+	}
+	_ = hook.configureClients("batching", func(client *raven.Client) error {
+		hook.wrapBatchTransport(client, cfg)
+		return nil
+	})
+}
+
+// wrapBatchTransport makes the client batch its sends. The batch transport
+// replaces the whole chain of transports of the client: it records the sends,
+// sends the attachments, spools the failed events and strips the parts a
+// legacy server rejects itself.
+func (hook *SentryHook) wrapBatchTransport(client *raven.Client, cfg BatchConfig) {
+	httpClient := http.DefaultClient
+	var compat *compatTransport
+	for _, transport := range transportChain(client.Transport) {
+		switch t := transport.(type) {
+		case *compatTransport:
+			compat = t
+		case *batchTransport:
+			t.flush()
+			httpClient = t.httpClient
+			if t.compat != nil {
+				compat = t.compat
+			}
+		case *raven.HTTPTransport:
+			if t.Client != nil {
+				httpClient = t.Client
+			}
+		}
+	}
+
+	client.Transport = &batchTransport{
+		hook:       hook,
+		config:     cfg,
+		httpClient: httpClient,
+		compat:     compat,
+	}
+}
+
+// clientBatch returns the batch transport of the client, or nil if it does
+// not batch its sends.
+func clientBatch(client *raven.Client) *batchTransport {
+	for _, transport := range transportChain(client.Transport) {
+		if t, ok := transport.(*batchTransport); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// flushBatches waits for the clients to hand over their queued events and
+// sends the pending batches.
+func (hook *SentryHook) flushBatches() {
+	for _, client := range hook.clients() {
+		flushClientBatch(client)
+	}
+}
+
+// flushClientBatch waits for the client to hand over its queued events and
+// sends its pending batch, if it batches its sends.
+func flushClientBatch(client *raven.Client) {
+	if t := clientBatch(client); t != nil {
+		client.Wait()
+		t.flush()
+	}
+}
+
+// batchFinalizers makes the finalizers of packet wait for the send of its
+// batch, if client batches its sends. It returns the finalizers to call with
+// the outcome of the capture instead, which only call them if the packet did
+// not reach the batch. The finalizers of split events are not deferred.
+func batchFinalizers(client *raven.Client, packet *raven.Packet, parts []*raven.Packet, fins Finalizers) Finalizers {
+	t := clientBatch(client)
+	if t == nil || len(fins) == 0 || len(parts) != 0 {
+		return fins
+	}
+
+	t.mu.Lock()
+	if t.finalizers == nil {
+		t.finalizers = make(map[*raven.Packet]Finalizers)
+	}
+	t.finalizers[packet] = fins
+	t.mu.Unlock()
+	return Finalizers{func(d Delivery) {
+		if d.Status == Delivered {
+			// added to the batch
+			return
+		}
+		if fins := t.takeFinalizers(packet); fins != nil {
+			fins.finish(d)
+		}
+	}}
+}
+
+// takeFinalizers returns and forgets the finalizers of packet.
+func (t *batchTransport) takeFinalizers(packet *raven.Packet) Finalizers {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fins := t.finalizers[packet]
+	delete(t.finalizers, packet)
+	return fins
+}
+
+// finalize calls the finalizers of packet in the background with the outcome
+// of its send, so that they may log even when the batch is sent by the
+// worker of the client.
+func (t *batchTransport) finalize(packet *raven.Packet, err error) {
+	fins := t.takeFinalizers(packet)
+	if fins == nil {
+		return
+	}
+	t.hook.finalizing.add()
+	go func() {
+		defer t.hook.finalizing.done()
+		fins.finish(delivered(packet.EventID, err))
+	}()
+}
+
+func (t *batchTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	if url == "" {
+		t.finalize(packet, nil)
+		return nil
+	}
+
+	t.mu.Lock()
+	t.pending = append(t.pending, batchedPacket{url, authHeader, packet})
+	if len(t.pending) < t.config.Size {
+		if t.timer == nil {
+			t.timer = time.AfterFunc(t.config.Interval, t.flush)
+		}
+		t.mu.Unlock()
+		return nil
+	}
+	batch := t.take()
+	t.mu.Unlock()
+
+	// sending in the client worker slows it down when batches fill up
+	// faster than they are sent
+	t.send(batch)
+	return nil
+}
+
+// take returns the pending packets. t.mu must be held.
+func (t *batchTransport) take() []batchedPacket {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	batch := t.pending
+	t.pending = nil
+	return batch
+}
+
+func (t *batchTransport) flush() {
+	t.mu.Lock()
+	batch := t.take()
+	t.mu.Unlock()
+	t.send(batch)
+}
+
+func (t *batchTransport) send(batch []batchedPacket) {
+	t.sendMu.Lock()
+	defer t.sendMu.Unlock()
+
+	for _, p := range batch {
+		start := time.Now()
+		err := t.sendPacket(p, t.hook.takeAttachments(p.packet))
+		t.hook.stats.recordSend(time.Since(start), err)
+		t.finalize(p.packet, err)
+		if err == nil {
+			continue
+		}
+		if t.hook.spool != nil && isRetryable(err) {
+			t.hook.spool.add(p.url, p.authHeader, p.packet)
+		}
+		if t.config.OnError != nil {
+			t.config.OnError(p.packet, err)
+		}
+		if len(t.hook.errorHandlers) != 0 {
+			entry := t.hook.packetEntry(p.packet)
+			for _, handlerFn := range t.hook.errorHandlers {
+				handlerFn(entry, err)
+			}
+		}
+	}
+}
+
+// packetEntry rebuilds an entry from packet, for the error handlers of the
+// events whose entry is gone.
+func (hook *SentryHook) packetEntry(packet *raven.Packet) *logrus.Entry {
+	entry := logrus.NewEntry(logrus.StandardLogger())
+	entry.Message = packet.Message
+	entry.Time = time.Time(packet.Timestamp)
+	// the least severe level of the severity of the packet
+	for _, level := range logrus.AllLevels {
+		if hook.severity(level) == packet.Level {
+			entry.Level = level
+		}
+	}
+	return entry
+}
+
+// sendPacket sends the packet of p, stripping the parts a legacy server
+// rejects, and sending it again if the server rejects new ones.
+func (t *batchTransport) sendPacket(p batchedPacket, attachments []Attachment) error {
+	if t.compat == nil {
+		return sendEnvelope(t.httpClient, p.url, p.authHeader, p.packet, attachments)
+	}
+	t.compat.strip(p.packet)
+	err := sendEnvelope(t.httpClient, p.url, p.authHeader, p.packet, attachments)
+	if err == nil || !t.compat.learn(err, p.packet) {
+		return err
+	}
+	t.compat.strip(p.packet)
+	return sendEnvelope(t.httpClient, p.url, p.authHeader, p.packet, attachments)
+}
@@ -0,0 +1,170 @@
+package logrus_sentry
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableBatching(t *testing.T) {
+	a := assert.New(t)
+
+	var mu sync.Mutex
+	var messages []string
+	received := make(chan struct{}, 10)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		a.True(strings.HasSuffix(req.URL.Path, "/envelope/"), "events should be sent to the envelope endpoint")
+
+		r := bufio.NewReader(req.Body)
+		for i := 0; i < 2; i++ {
+			r.ReadString('\n') // envelope and item headers
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r).Decode(&body)
+		mu.Lock()
+		messages = append(messages, body["message"].(string))
+		mu.Unlock()
+		received <- struct{}{}
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.EnableBatching(BatchConfig{Size: 2, Interval: time.Hour})
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	logger.Error("first")
+	select {
+	case <-received:
+		t.Error("event should wait for its batch")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	logger.Error("second")
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			t.Fatal("full batch should be sent")
+		}
+	}
+
+	logger.Error("third")
+	hook.Flush()
+	a.Len(received, 1, "Flush should send the pending batch")
+
+	mu.Lock()
+	defer mu.Unlock()
+	a.Equal([]string{"first", "second", "third"}, messages, "events should be sent in order")
+}
+
+func TestEnableBatchingInterval(t *testing.T) {
+	a := assert.New(t)
+
+	errs := make(chan error, 1)
+	handled := make(chan *logrus.Entry, 1)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusBadRequest)
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.EnableBatching(BatchConfig{
+		Size:     100,
+		Interval: 10 * time.Millisecond,
+		OnError: func(packet *raven.Packet, err error) {
+			errs <- err
+		},
+	})
+	hook.AddErrorHandler(func(entry *logrus.Entry, err error) {
+		if err != nil {
+			handled <- entry
+		}
+	})
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+
+	select {
+	case err := <-errs:
+		a.Contains(err.Error(), "got http status 400")
+	case <-time.After(time.Second):
+		t.Error("batch should be sent after the interval")
+	}
+	select {
+	case entry := <-handled:
+		a.Equal(message, entry.Message)
+		a.Equal(logrus.ErrorLevel, entry.Level)
+	case <-time.After(time.Second):
+		t.Error("send errors should be reported to the error handlers")
+	}
+}
+
+func TestEnableBatchingFinalizers(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusBadRequest)
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.EnableBatching(BatchConfig{Size: 100, Interval: time.Hour})
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	var mu sync.Mutex
+	var deliveries []Delivery
+	a.NoError(hook.Fire(&logrus.Entry{
+		Logger:  logger,
+		Level:   logrus.ErrorLevel,
+		Message: message,
+		Data: logrus.Fields{fieldFinalizers: Finalizers{func(d Delivery) {
+			mu.Lock()
+			defer mu.Unlock()
+			deliveries = append(deliveries, d)
+		}}},
+	}), "Fire should not wait for the batch")
+	mu.Lock()
+	a.Empty(deliveries, "the finalizers should wait for the batch")
+	mu.Unlock()
+
+	hook.Flush()
+	mu.Lock()
+	defer mu.Unlock()
+	if a.Len(deliveries, 1, "the finalizers should be called once the batch is sent") {
+		a.Equal(DeliveryFailed, deliveries[0].Status)
+		a.NotEmpty(deliveries[0].EventID)
+		a.Error(deliveries[0].Err)
+	}
+}
+
+func TestEnvelopeURL(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("https://sentry.io/api/1/envelope/", envelopeURL("https://sentry.io/api/1/store/"))
+	a.Equal("https://sentry.io/custom", envelopeURL("https://sentry.io/custom"))
+}
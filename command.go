@@ -0,0 +1,148 @@
+package logrus_sentry
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// fieldCmd is the field holding the *exec.Cmd of a failed command.
+	fieldCmd = "cmd"
+	// extraCommand is the extra describing a failed command.
+	extraCommand = "command"
+
+	// maxCommandStderr is the number of trailing bytes of the standard
+	// error of a command which are sent.
+	maxCommandStderr = 4 << 10
+)
+
+// sensitiveArgNames are the parts of the names of the command line flags
+// whose values are scrubbed.
+var sensitiveArgNames = []string{
+	"password", "passwd", "secret", "token", "auth", "credential",
+	"apikey", "api-key", "api_key", "private-key", "private_key",
+}
+
+func (d *dataField) getCmd() (*exec.Cmd, bool) {
+	if cmd, ok := d.data[fieldCmd].(*exec.Cmd); ok && cmd != nil {
+		d.omitList[fieldCmd] = struct{}{}
+		return cmd, true
+	}
+	return nil, false
+}
+
+// commandInfo describes the command of cmd, or of the *exec.ExitError found
+// in the chain of err: its path, scrubbed arguments, exit code, signal and the
+// end of its standard error. cmd and err may be nil.
+func commandInfo(cmd *exec.Cmd, err error) (map[string]interface{}, bool) {
+	var exitErr *exec.ExitError
+	for e := err; e != nil; e = nextCause(e) {
+		if ee, ok := e.(*exec.ExitError); ok && ee != nil {
+			exitErr = ee
+			break
+		}
+	}
+	if cmd == nil && exitErr == nil {
+		return nil, false
+	}
+
+	info := make(map[string]interface{})
+	var stderr []byte
+	if cmd != nil {
+		info["path"] = cmd.Path
+		info["args"] = scrubArgs(cmd.Args)
+		switch w := cmd.Stderr.(type) {
+		case *bytes.Buffer:
+			stderr = w.Bytes()
+		case *strings.Builder:
+			stderr = []byte(w.String())
+		}
+	}
+	var state *os.ProcessState
+	if exitErr != nil {
+		state = exitErr.ProcessState
+	} else if cmd != nil {
+		state = cmd.ProcessState
+	}
+	if state != nil {
+		info["exit_code"] = state.ExitCode()
+		// the state of a process killed by a signal reads "signal: killed"
+		if s := state.String(); strings.HasPrefix(s, "signal: ") {
+			info["signal"] = strings.TrimPrefix(s, "signal: ")
+		}
+	}
+	if exitErr != nil && len(exitErr.Stderr) != 0 {
+		stderr = exitErr.Stderr
+	}
+	if len(stderr) != 0 {
+		info["stderr"] = tailString(string(stderr), maxCommandStderr)
+	}
+	return info, true
+}
+
+// scrubArgs returns args with the values of the sensitive flags and the
+// credentials of URLs replaced by "[Filtered]".
+func scrubArgs(args []string) []string {
+	scrubbed := make([]string, len(args))
+	scrubNext := false
+	for i, arg := range args {
+		switch {
+		case scrubNext:
+			arg = scrubbedValue
+			scrubNext = false
+		case strings.Contains(arg, "="):
+			name := arg[:strings.Index(arg, "=")]
+			if isSensitiveArg(name) {
+				arg = name + "=" + scrubbedValue
+			}
+		case strings.HasPrefix(arg, "-"):
+			scrubNext = isSensitiveArg(arg)
+		}
+		scrubbed[i] = scrubURLCredentials(arg)
+	}
+	return scrubbed
+}
+
+func isSensitiveArg(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitiveArgNames {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubURLCredentials replaces the user info of the URL in arg, if any.
+func scrubURLCredentials(arg string) string {
+	i := strings.Index(arg, "://")
+	if i < 0 {
+		return arg
+	}
+	rest := arg[i+3:]
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	at := strings.LastIndex(rest[:end], "@")
+	if at < 0 {
+		return arg
+	}
+	return arg[:i+3] + scrubbedValue + rest[at:]
+}
+
+// tailString returns the longest suffix of s of at most n bytes which does
+// not split a UTF-8 sequence, prefixed with "..." if s was truncated.
+func tailString(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := len(s) - n
+	for i < len(s) && !utf8.RuneStart(s[i]) {
+		i++
+	}
+	return "..." + s[i:]
+}
@@ -0,0 +1,90 @@
+package logrus_sentry
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrubArgs(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]string{
+		"psql",
+		"--password=[Filtered]",
+		"--auth-token", "[Filtered]",
+		"-v", "ON_ERROR_STOP=1",
+		"DB_PASSWORD=[Filtered]",
+		"postgres://[Filtered]@db:5432/app?sslmode=disable",
+		"https://example.com/a@b",
+	}, scrubArgs([]string{
+		"psql",
+		"--password=hunter2",
+		"--auth-token", "abc",
+		"-v", "ON_ERROR_STOP=1",
+		"DB_PASSWORD=hunter2",
+		"postgres://app:hunter2@db:5432/app?sslmode=disable",
+		"https://example.com/a@b",
+	}))
+}
+
+func TestTailString(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("short", tailString("short", 10))
+	a.Equal("...fghij", tailString("abcdefghij", 5))
+	a.Equal("...b", tailString("aéb", 2), "a UTF-8 sequence should not be split")
+}
+
+func TestCommandInfo(t *testing.T) {
+	a := assert.New(t)
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		cmd := exec.Command("sh", "-c", "echo migration failed >&2; exit 3", "--token=abc")
+		_, err = cmd.Output()
+		logger.WithError(errors.Wrap(err, "migrate")).Error(message)
+		packet := <-pch
+		info, ok := packet.Extra[extraCommand].(map[string]interface{})
+		if a.True(ok, "the exit error should be described") {
+			a.Equal(float64(3), info["exit_code"])
+			a.Equal("migration failed\n", info["stderr"])
+			a.NotContains(info, "signal")
+		}
+
+		var stderr bytes.Buffer
+		cmd = exec.Command("sh", "-c", "echo $1 >&2; kill -9 $$", "sh", "--password=hunter2")
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		logger.WithError(err).WithField(fieldCmd, cmd).Error(message)
+		packet = <-pch
+		info, ok = packet.Extra[extraCommand].(map[string]interface{})
+		if a.True(ok, "the command should be described") {
+			a.Equal(cmd.Path, info["path"])
+			a.Equal([]interface{}{"sh", "-c", "echo $1 >&2; kill -9 $$", "sh", "--password=[Filtered]"}, info["args"])
+			a.Equal(float64(-1), info["exit_code"])
+			a.Equal("killed", info["signal"])
+			a.True(strings.HasPrefix(info["stderr"].(string), "--password=hunter2"), "stderr is sent as is")
+		}
+		a.NotContains(packet.Extra, fieldCmd, "the cmd field should not be sent as extra")
+
+		logger.WithError(errors.New("not a command")).Error(message)
+		packet = <-pch
+		a.NotContains(packet.Extra, extraCommand)
+	})
+}
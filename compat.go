@@ -0,0 +1,220 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/musqdp/raven-go"
+)
+
+// serverVersion is a sentry server version. Self-hosted releases use
+// calendar versions (e.g. 21.6) since 20.6, which still compare correctly.
+type serverVersion struct {
+	major, minor int
+}
+
+func (v serverVersion) less(o serverVersion) bool {
+	return v.major < o.major || (v.major == o.major && v.minor < o.minor)
+}
+
+func parseServerVersion(s string) (serverVersion, error) {
+	var v serverVersion
+	if _, err := fmt.Sscanf(s, "%d.%d", &v.major, &v.minor); err != nil {
+		return v, fmt.Errorf("invalid sentry version %q", s)
+	}
+	return v, nil
+}
+
+var (
+	// interfaceVersions are the first sentry versions accepting the
+	// interfaces which older installs reject.
+	interfaceVersions = map[string]serverVersion{
+		"breadcrumbs": {8, 5},
+		"contexts":    {8, 12},
+		"dist":        {8, 21},
+		"transaction": {9, 0},
+	}
+	// contextVersions are the first sentry versions accepting the contexts
+	// which older installs reject.
+	contextVersions = map[string]serverVersion{
+		contextTrace: {20, 7},
+	}
+)
+
+// compatTransport strips the interfaces and contexts a legacy sentry server
+// rejects. They are known from the server version, or learned from the
+// X-Sentry-Error header of rejected events.
+type compatTransport struct {
+	raven.Transport
+
+	mu       sync.RWMutex
+	rejected map[string]struct{}
+}
+
+// EnableLegacyCompat avoids sending the interfaces and contexts which older
+// self-hosted sentry servers reject with a 400 error, dropping the whole
+// event. serverVersion, e.g. "9.1", selects what is stripped upfront; if it
+// is empty, the rejected parts are detected from the server responses and
+// the event is sent again without them.
+//
+// With batching, it must be called before EnableBatching: it returns an
+// error once the hook batches its sends.
+func (hook *SentryHook) EnableLegacyCompat(serverVersion string) error {
+	if clientBatch(hook.client) != nil {
+		return errors.New("legacy compatibility must be enabled before batching")
+	}
+	rejected := make(map[string]struct{})
+	if serverVersion != "" {
+		v, err := parseServerVersion(serverVersion)
+		if err != nil {
+			return err
+		}
+		for class, since := range interfaceVersions {
+			if v.less(since) {
+				rejected[class] = struct{}{}
+			}
+		}
+		for name, since := range contextVersions {
+			if v.less(since) {
+				rejected[compatContextKey(name)] = struct{}{}
+			}
+		}
+	}
+
+	return hook.configureClients("legacy_compat", func(client *raven.Client) error {
+		if t, ok := client.Transport.(*compatTransport); ok {
+			client.Transport = t.Transport
+		}
+		learned := make(map[string]struct{}, len(rejected))
+		for k := range rejected {
+			learned[k] = struct{}{}
+		}
+		client.Transport = &compatTransport{
+			Transport: client.Transport,
+			rejected:  learned,
+		}
+		return nil
+	})
+}
+
+func compatContextKey(name string) string {
+	return "contexts." + name
+}
+
+func (t *compatTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	t.strip(packet)
+	err := t.Transport.Send(url, authHeader, packet)
+	if err == nil || !t.learn(err, packet) {
+		return err
+	}
+	t.strip(packet)
+	return t.Transport.Send(url, authHeader, packet)
+}
+
+func (t *compatTransport) isRejected(key string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	_, ok := t.rejected[key]
+	return ok
+}
+
+// strip removes the rejected interfaces and contexts from packet.
+func (t *compatTransport) strip(packet *raven.Packet) {
+	interfaces := packet.Interfaces[:0]
+	for _, in := range packet.Interfaces {
+		if t.isRejected(in.Class()) {
+			continue
+		}
+		if contexts, ok := in.(contextsInterface); ok {
+			in = t.stripContexts(contexts)
+			if in == nil {
+				continue
+			}
+		}
+		interfaces = append(interfaces, in)
+	}
+	packet.Interfaces = interfaces
+}
+
+func (t *compatTransport) stripContexts(contexts contextsInterface) raven.Interface {
+	kept := make(contextsInterface, len(contexts))
+	for name, value := range contexts {
+		if !t.isRejected(compatContextKey(name)) {
+			kept[name] = value
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// learn marks as rejected the interfaces and contexts of packet named by the
+// X-Sentry-Error of a 400 error. It reports whether any was found.
+func (t *compatTransport) learn(err error, packet *raven.Packet) bool {
+	var status int
+	var reason string
+	if _, scanErr := fmt.Sscanf(err.Error(), "raven: got http status %d", &status); scanErr != nil || status != http.StatusBadRequest {
+		return false
+	}
+	if i := strings.Index(err.Error(), "x-sentry-error:"); i >= 0 {
+		reason = err.Error()[i+len("x-sentry-error:"):]
+	}
+
+	var keys []string
+	for _, in := range packet.Interfaces {
+		class := in.Class()
+		if _, ok := interfaceVersions[class]; ok && mentions(reason, class) {
+			keys = append(keys, class)
+		}
+		if contexts, ok := in.(contextsInterface); ok {
+			for name := range contexts {
+				if _, ok := contextVersions[name]; ok && mentions(reason, name) {
+					keys = append(keys, compatContextKey(name))
+				}
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, key := range keys {
+		t.rejected[key] = struct{}{}
+	}
+	return true
+}
+
+// mentions reports whether the error reason contains name as a word.
+func mentions(reason, name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; ; {
+		j := strings.Index(reason[i:], name)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(name)
+		if isWordBoundary(reason, start) && isWordBoundary(reason, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+// isWordBoundary reports whether s has a word boundary at i, like \b.
+func isWordBoundary(s string, i int) bool {
+	before := i > 0 && isWordByte(s[i-1])
+	after := i < len(s) && isWordByte(s[i])
+	return before != after
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
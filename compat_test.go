@@ -0,0 +1,148 @@
+package logrus_sentry
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableLegacyCompat(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		version  string
+		expected []string
+	}{
+		{"8.4.0", []string{"breadcrumbs", "contexts", "dist", "transaction", "contexts.trace"}},
+		{"8.22", []string{"transaction", "contexts.trace"}},
+		{"9.1", []string{"contexts.trace"}},
+		{"21.6.1", []string{}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		hook, err := NewSentryHook("", []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		a.NoError(hook.EnableLegacyCompat(tt.version))
+
+		transport := hook.client.Transport.(*compatTransport)
+		rejected := []string{}
+		for _, key := range []string{"breadcrumbs", "contexts", "dist", "transaction", "contexts.trace"} {
+			if transport.isRejected(key) {
+				rejected = append(rejected, key)
+			}
+		}
+		a.Equal(tt.expected, rejected, tt.version)
+	}
+
+	hook, _ := NewSentryHook("", nil)
+	a.Error(hook.EnableLegacyCompat("nine"), "invalid version should be an error")
+}
+
+func TestEnableLegacyCompatDetection(t *testing.T) {
+	a := assert.New(t)
+
+	received := make(chan map[string]interface{}, 2)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		if _, ok := body["dist"]; ok {
+			rw.Header().Set("X-Sentry-Error", "Invalid interface: 'dist'")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- body
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.SetDist("42")
+	a.NoError(hook.EnableLegacyCompat(""))
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+
+	body := <-received
+	a.Equal(message, body["message"], "event should be sent again without the rejected interface")
+	a.True(hook.client.Transport.(*compatTransport).isRejected("dist"))
+
+	packet := &raven.Packet{Interfaces: []raven.Interface{
+		distInterface("42"),
+		contextsInterface{contextTrace: map[string]interface{}{}, "os": map[string]interface{}{}},
+	}}
+	hook.client.Transport.(*compatTransport).rejected[compatContextKey(contextTrace)] = struct{}{}
+	hook.client.Transport.(*compatTransport).strip(packet)
+	a.Equal([]raven.Interface{contextsInterface{"os": map[string]interface{}{}}}, packet.Interfaces)
+}
+
+func TestLegacyCompatBatching(t *testing.T) {
+	a := assert.New(t)
+
+	received := make(chan map[string]interface{}, 2)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		r := bufio.NewReader(req.Body)
+		for i := 0; i < 2; i++ {
+			r.ReadString('\n') // envelope and item headers
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r).Decode(&body)
+		if _, ok := body["dist"]; ok {
+			rw.Header().Set("X-Sentry-Error", "Invalid interface: 'dist'")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- body
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.SetDist("42")
+	a.NoError(hook.EnableLegacyCompat("9.1"))
+	hook.EnableBatching(BatchConfig{Size: 10, Interval: time.Hour})
+	a.Error(hook.EnableLegacyCompat("9.1"), "legacy compatibility should be enabled before batching")
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+	hook.Flush()
+
+	select {
+	case body := <-received:
+		a.Equal(message, body["message"], "event should be sent again without the rejected interface")
+	default:
+		t.Fatal("Flush should send the pending batch")
+	}
+	batch := clientBatch(hook.client)
+	if a.NotNil(batch) && a.NotNil(batch.compat, "batching should keep the legacy compatibility") {
+		a.True(batch.compat.isRejected("dist"))
+	}
+}
+
+func TestMentions(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(mentions("Discarded invalid value for parameter 'exception'", "exception"))
+	a.True(mentions("exception", "exception"))
+	a.True(mentions("unknown interface: sentry.interfaces.Exception, exception", "exception"))
+	a.False(mentions("invalid exceptions", "exception"), "a longer word should not match")
+	a.False(mentions("invalid my_exception", "exception"), "a longer word should not match")
+	a.True(mentions("invalid my_exception, exception", "exception"))
+	a.False(mentions("invalid request", "exception"))
+}
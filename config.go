@@ -0,0 +1,288 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// scrubbedValue replaces the values of the scrubbed fields.
+const scrubbedValue = "[Filtered]"
+
+// Config declares the configuration of a hook. It can be loaded from JSON
+// with LoadJSON, from YAML with LoadYAML when built with the yaml tag, or
+// with any YAML library honoring the yaml tags, and from environment
+// variables with LoadEnv.
+type Config struct {
+	DSN string `json:"dsn" yaml:"dsn"`
+	// Levels are the names of the logrus levels sent to sentry.
+	// Defaults to panic, fatal and error.
+	Levels      []string          `json:"levels" yaml:"levels"`
+	Tags        map[string]string `json:"tags" yaml:"tags"`
+	Environment string            `json:"environment" yaml:"environment"`
+	Release     string            `json:"release" yaml:"release"`
+	Dist        string            `json:"dist" yaml:"dist"`
+	ServerName  string            `json:"server_name" yaml:"server_name"`
+
+	// Timeout is the time to wait for the delivery of an event.
+	// Defaults to 100ms when nil, and zero does not wait.
+	Timeout *Duration `json:"timeout" yaml:"timeout"`
+	Async   bool      `json:"async" yaml:"async"`
+	// AsyncLimit caps the number of events an asynchronous hook sends at
+	// once. Overflow is "drop" (default), "block" or "sync", and
+	// OverflowTimeout the timeout of "block".
+	AsyncLimit      int      `json:"async_limit" yaml:"async_limit"`
+	Overflow        string   `json:"overflow" yaml:"overflow"`
+	OverflowTimeout Duration `json:"overflow_timeout" yaml:"overflow_timeout"`
+	// SampleRate is the rate of events sent, between 0 and 1.
+	// Zero means all events are sent.
+	SampleRate float32 `json:"sample_rate" yaml:"sample_rate"`
+
+	// IgnoreErrors are regular expressions of the messages not sent.
+	IgnoreErrors []string `json:"ignore_errors" yaml:"ignore_errors"`
+	// IgnoreFields are the fields not sent as extra.
+	IgnoreFields []string `json:"ignore_fields" yaml:"ignore_fields"`
+	// ScrubFields are the fields whose values are replaced by "[Filtered]".
+	ScrubFields []string `json:"scrub_fields" yaml:"scrub_fields"`
+
+	Stacktrace    bool     `json:"stacktrace" yaml:"stacktrace"`
+	InAppPrefixes []string `json:"in_app_prefixes" yaml:"in_app_prefixes"`
+	Strict        bool     `json:"strict" yaml:"strict"`
+	// GoroutineInfo adds the ID and creation point of the goroutine which
+	// fired the events of the error level and above as extras.
+	GoroutineInfo bool `json:"goroutine_info" yaml:"goroutine_info"`
+
+	// WarmUp is the timeout of the warm up of the connection to the sentry
+	// server when the hook is created. Zero skips the warm up, whose
+	// failure is ignored.
+	WarmUp Duration `json:"warm_up" yaml:"warm_up"`
+	// HealthReport is the interval of the reports of the dropped events.
+	// Zero disables them.
+	HealthReport Duration `json:"health_report" yaml:"health_report"`
+}
+
+// Duration is a time.Duration read from strings like "100ms".
+type Duration time.Duration
+
+// UnmarshalText parses a duration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalText formats the duration.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// LoadJSON sets the fields of cfg present in the JSON object b.
+func (cfg *Config) LoadJSON(b []byte) error {
+	return json.Unmarshal(b, cfg)
+}
+
+// LoadEnv sets the fields of cfg from the environment variables named after
+// their JSON keys, upper-cased and prefixed, e.g. SENTRY_DSN or
+// SENTRY_SAMPLE_RATE with the prefix "SENTRY". Lists are comma separated,
+// and tags are key=value pairs. Missing variables leave fields unchanged.
+func (cfg *Config) LoadEnv(prefix string) error {
+	env := func(name string) (string, bool) {
+		return os.LookupEnv(prefix + "_" + name)
+	}
+	list := func(s string) []string {
+		var values []string
+		for _, v := range strings.Split(s, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	strs := map[string]*string{
+		"DSN":         &cfg.DSN,
+		"ENVIRONMENT": &cfg.Environment,
+		"RELEASE":     &cfg.Release,
+		"DIST":        &cfg.Dist,
+		"SERVER_NAME": &cfg.ServerName,
+		"OVERFLOW":    &cfg.Overflow,
+	}
+	for name, field := range strs {
+		if v, ok := env(name); ok {
+			*field = v
+		}
+	}
+	lists := map[string]*[]string{
+		"LEVELS":          &cfg.Levels,
+		"IGNORE_ERRORS":   &cfg.IgnoreErrors,
+		"IGNORE_FIELDS":   &cfg.IgnoreFields,
+		"SCRUB_FIELDS":    &cfg.ScrubFields,
+		"IN_APP_PREFIXES": &cfg.InAppPrefixes,
+	}
+	for name, field := range lists {
+		if v, ok := env(name); ok {
+			*field = list(v)
+		}
+	}
+	bools := map[string]*bool{
+		"ASYNC":          &cfg.Async,
+		"STACKTRACE":     &cfg.Stacktrace,
+		"STRICT":         &cfg.Strict,
+		"GOROUTINE_INFO": &cfg.GoroutineInfo,
+	}
+	for name, field := range bools {
+		if v, ok := env(name); ok {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%s_%s: %v", prefix, name, err)
+			}
+			*field = b
+		}
+	}
+	durations := map[string]*Duration{
+		"OVERFLOW_TIMEOUT": &cfg.OverflowTimeout,
+		"WARM_UP":          &cfg.WarmUp,
+		"HEALTH_REPORT":    &cfg.HealthReport,
+	}
+	for name, field := range durations {
+		if v, ok := env(name); ok {
+			if err := field.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("%s_%s: %v", prefix, name, err)
+			}
+		}
+	}
+	if v, ok := env("TIMEOUT"); ok {
+		var timeout Duration
+		if err := timeout.UnmarshalText([]byte(v)); err != nil {
+			return fmt.Errorf("%s_TIMEOUT: %v", prefix, err)
+		}
+		cfg.Timeout = &timeout
+	}
+	if v, ok := env("ASYNC_LIMIT"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s_ASYNC_LIMIT: %v", prefix, err)
+		}
+		cfg.AsyncLimit = n
+	}
+	if v, ok := env("SAMPLE_RATE"); ok {
+		rate, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return fmt.Errorf("%s_SAMPLE_RATE: %v", prefix, err)
+		}
+		cfg.SampleRate = float32(rate)
+	}
+	if v, ok := env("TAGS"); ok {
+		cfg.Tags = make(map[string]string)
+		for _, pair := range list(v) {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("%s_TAGS: invalid tag %q", prefix, pair)
+			}
+			cfg.Tags[kv[0]] = kv[1]
+		}
+	}
+	return nil
+}
+
+// NewSentryHookFromConfig creates a hook configured by cfg.
+func NewSentryHookFromConfig(cfg Config) (*SentryHook, error) {
+	levels := []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel}
+	if len(cfg.Levels) != 0 {
+		levels = levels[:0]
+		for _, name := range cfg.Levels {
+			level, err := logrus.ParseLevel(name)
+			if err != nil {
+				return nil, err
+			}
+			levels = append(levels, level)
+		}
+	}
+
+	client, err := raven.NewWithTags(cfg.DSN, cfg.Tags)
+	if err != nil {
+		return nil, err
+	}
+	hook, err := NewWithClientSentryHook(client, levels)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Async {
+		setAsync(hook)
+	}
+
+	if cfg.Environment != "" {
+		hook.SetEnvironment(cfg.Environment)
+	}
+	if cfg.Release != "" {
+		hook.SetRelease(cfg.Release)
+	}
+	if cfg.Dist != "" {
+		hook.SetDist(cfg.Dist)
+	}
+	if cfg.ServerName != "" {
+		hook.SetServerName(cfg.ServerName)
+	}
+	if cfg.Timeout != nil {
+		hook.Timeout = time.Duration(*cfg.Timeout)
+	}
+	if cfg.AsyncLimit != 0 {
+		policy, err := parseOverflowPolicy(cfg.Overflow)
+		if err != nil {
+			return nil, err
+		}
+		if err := hook.SetAsyncLimit(cfg.AsyncLimit, policy, time.Duration(cfg.OverflowTimeout)); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SampleRate != 0 {
+		if err := hook.SetSampleRate(cfg.SampleRate); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.IgnoreErrors) != 0 {
+		if err := hook.SetIgnoreErrors(cfg.IgnoreErrors...); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range cfg.IgnoreFields {
+		hook.AddIgnore(name)
+	}
+	for _, name := range cfg.ScrubFields {
+		hook.AddExtraFilter(name, func(interface{}) interface{} { return scrubbedValue })
+	}
+	hook.StacktraceConfiguration.Enable = cfg.Stacktrace
+	if len(cfg.InAppPrefixes) != 0 {
+		hook.StacktraceConfiguration.InAppPrefixes = cfg.InAppPrefixes
+	}
+	hook.SetStrict(cfg.Strict)
+	hook.SetGoroutineInfo(cfg.GoroutineInfo)
+	hook.EnableHealthReport(time.Duration(cfg.HealthReport))
+	if cfg.WarmUp > 0 {
+		// the hook connects again on the first event
+		_ = hook.WarmUp(time.Duration(cfg.WarmUp))
+	}
+	return hook, nil
+}
+
+func parseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch s {
+	case "", "drop":
+		return OverflowDrop, nil
+	case "block":
+		return OverflowBlock, nil
+	case "sync":
+		return OverflowSync, nil
+	default:
+		return 0, fmt.Errorf("invalid overflow policy %q", s)
+	}
+}
@@ -0,0 +1,99 @@
+package logrus_sentry
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigLoad(t *testing.T) {
+	a := assert.New(t)
+
+	var cfg Config
+	a.NoError(cfg.LoadJSON([]byte(`{
+		"levels": ["error", "warning"],
+		"tags": {"site": "json"},
+		"environment": "staging",
+		"timeout": "2s",
+		"scrub_fields": ["password"]
+	}`)))
+
+	for key, value := range map[string]string{
+		"TEST_SENTRY_ENVIRONMENT": "production",
+		"TEST_SENTRY_TAGS":        "site=env, team=core",
+		"TEST_SENTRY_ASYNC":       "true",
+		"TEST_SENTRY_SAMPLE_RATE": "0.5",
+		"TEST_SENTRY_WARM_UP":     "500ms",
+	} {
+		os.Setenv(key, value)
+		defer os.Unsetenv(key)
+	}
+	a.NoError(cfg.LoadEnv("TEST_SENTRY"))
+
+	a.Equal([]string{"error", "warning"}, cfg.Levels)
+	a.Equal(map[string]string{"site": "env", "team": "core"}, cfg.Tags)
+	a.Equal("production", cfg.Environment)
+	if a.NotNil(cfg.Timeout) {
+		a.Equal(Duration(2*time.Second), *cfg.Timeout)
+	}
+	a.Equal([]string{"password"}, cfg.ScrubFields)
+	a.True(cfg.Async)
+	a.Equal(float32(0.5), cfg.SampleRate)
+	a.Equal(Duration(500*time.Millisecond), cfg.WarmUp)
+
+	os.Setenv("TEST_SENTRY_TIMEOUT", "soon")
+	defer os.Unsetenv("TEST_SENTRY_TIMEOUT")
+	a.Error(cfg.LoadEnv("TEST_SENTRY"), "invalid duration should be an error")
+}
+
+func TestNewSentryHookFromConfig(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		timeout := Duration(time.Second)
+		hook, err := NewSentryHookFromConfig(Config{
+			DSN:          dsn,
+			Levels:       []string{"warning"},
+			Tags:         map[string]string{"site": "config"},
+			Environment:  "staging",
+			Release:      "v1.2.3",
+			Timeout:      &timeout,
+			IgnoreFields: []string{"ignored"},
+			ScrubFields:  []string{"password"},
+		})
+		a.NoError(err, "NewSentryHookFromConfig should be NoError")
+		a.Equal([]logrus.Level{logrus.WarnLevel}, hook.Levels())
+		a.Equal(time.Second, hook.Timeout)
+		logger.Hooks.Add(hook)
+
+		logger.WithFields(logrus.Fields{
+			"ignored":  "value",
+			"password": "hunter2",
+		}).Warn(message)
+		packet := <-pch
+		a.Equal("staging", packet.Environment)
+		a.Equal("v1.2.3", packet.Release)
+		a.Contains(packet.Tags, raven.Tag{Key: "site", Value: "config"})
+		a.NotContains(packet.Extra, "ignored")
+		a.Equal(scrubbedValue, packet.Extra["password"])
+	})
+
+	hook, err := NewSentryHookFromConfig(Config{})
+	a.NoError(err, "NewSentryHookFromConfig should be NoError")
+	a.Equal(100*time.Millisecond, hook.Timeout, "a missing timeout should keep the default")
+	noWait := Duration(0)
+	hook, err = NewSentryHookFromConfig(Config{Timeout: &noWait})
+	a.NoError(err, "NewSentryHookFromConfig should be NoError")
+	a.Equal(time.Duration(0), hook.Timeout, "a zero timeout should not wait")
+
+	_, err = NewSentryHookFromConfig(Config{Levels: []string{"loud"}})
+	a.Error(err, "invalid level should be an error")
+
+	_, err = NewSentryHookFromConfig(Config{AsyncLimit: 1, Overflow: "spill"})
+	a.Error(err, "invalid overflow policy should be an error")
+}
@@ -0,0 +1,12 @@
+//go:build yaml
+// +build yaml
+
+package logrus_sentry
+
+import "go.yaml.in/yaml/v3"
+
+// LoadYAML sets the fields of cfg present in the YAML document b. It is only
+// built with the yaml build tag.
+func (cfg *Config) LoadYAML(b []byte) error {
+	return yaml.Unmarshal(b, cfg)
+}
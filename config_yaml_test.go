@@ -0,0 +1,32 @@
+//go:build yaml
+// +build yaml
+
+package logrus_sentry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigLoadYAML(t *testing.T) {
+	a := assert.New(t)
+
+	var cfg Config
+	a.NoError(cfg.LoadYAML([]byte(`
+levels: [error, warning]
+tags:
+  site: yaml
+timeout: 0s
+overflow_timeout: 2s
+`)))
+	a.Equal([]string{"error", "warning"}, cfg.Levels)
+	a.Equal(map[string]string{"site": "yaml"}, cfg.Tags)
+	if a.NotNil(cfg.Timeout) {
+		a.Equal(Duration(0), *cfg.Timeout)
+	}
+	a.Equal(Duration(2*time.Second), cfg.OverflowTimeout)
+
+	a.Error(cfg.LoadYAML([]byte(`timeout: soon`)), "invalid duration should be an error")
+}
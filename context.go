@@ -0,0 +1,107 @@
+package logrus_sentry
+
+import (
+	"context"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	// extraContextCancellation is the extra key holding the details of the
+	// cancellation of the entry's context.
+	extraContextCancellation = "context_cancellation"
+
+	// tagTraceID is the tag holding the trace ID set by WithTraceID.
+	tagTraceID = "trace_id"
+)
+
+type contextKey int
+
+const (
+	contextKeyStartTime contextKey = iota
+	contextKeyTags
+	contextKeyUser
+	contextKeyTraceID
+)
+
+// WithTags returns a copy of ctx carrying tags, merged with the tags ctx
+// already carries. They are added to the events fired with the context,
+// unless the entry sets the same tags.
+func WithTags(ctx context.Context, tags map[string]string) context.Context {
+	merged := make(map[string]string)
+	for k, v := range tagsFromContext(ctx) {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, contextKeyTags, merged)
+}
+
+// WithUser returns a copy of ctx carrying user. It is set on the events fired
+// with the context which have no user fields.
+func WithUser(ctx context.Context, user *raven.User) context.Context {
+	return context.WithValue(ctx, contextKeyUser, user)
+}
+
+// WithTraceID returns a copy of ctx carrying a trace ID. It is set as the
+// trace_id tag of the events fired with the context.
+func WithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, contextKeyTraceID, traceID)
+}
+
+func tagsFromContext(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(contextKeyTags).(map[string]string)
+	return tags
+}
+
+// applyContext merges the values set by WithTags, WithUser and WithTraceID
+// into the packet. Values already set from the entry's fields win.
+func applyContext(ctx context.Context, packet *raven.Packet, hasUser bool) {
+	tags := make(map[string]string)
+	for k, v := range tagsFromContext(ctx) {
+		tags[k] = v
+	}
+	if traceID, ok := ctx.Value(contextKeyTraceID).(string); ok && traceID != "" {
+		tags[tagTraceID] = traceID
+	}
+	for _, tag := range packet.Tags {
+		delete(tags, tag.Key)
+	}
+	packet.AddTags(tags)
+
+	if user, ok := ctx.Value(contextKeyUser).(*raven.User); ok && user != nil && !hasUser {
+		packet.Interfaces = append(packet.Interfaces, user)
+	}
+}
+
+// WithStartTime returns a copy of ctx recording the current time, so that
+// events fired with a cancelled ctx report how long it lived.
+func WithStartTime(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKeyStartTime, time.Now())
+}
+
+// contextCancellation returns the details of the cancellation of ctx, or
+// false if ctx is not cancelled.
+func contextCancellation(ctx context.Context, now time.Time) (map[string]interface{}, bool) {
+	err := ctx.Err()
+	if err == nil {
+		return nil, false
+	}
+
+	info := map[string]interface{}{
+		"error": err.Error(),
+	}
+	if cause := contextCause(ctx); cause != nil && cause != err {
+		info["cause"] = cause.Error()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		info["deadline"] = deadline.Format(time.RFC3339Nano)
+		info["deadline_remaining"] = deadline.Sub(now).String()
+	}
+	if start, ok := ctx.Value(contextKeyStartTime).(time.Time); ok {
+		info["elapsed"] = now.Sub(start).String()
+	}
+	return info, true
+}
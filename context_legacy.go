@@ -0,0 +1,14 @@
+//go:build !go1.20
+// +build !go1.20
+
+package logrus_sentry
+
+import (
+	"context"
+)
+
+// contextCause returns the cause of the cancellation of ctx, which is only
+// available since go1.20.
+func contextCause(ctx context.Context) error {
+	return ctx.Err()
+}
@@ -0,0 +1,124 @@
+package logrus_sentry
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextCancellation(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	_, ok := contextCancellation(context.Background(), now)
+	a.False(ok, "active context should not be reported")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	info, ok := contextCancellation(ctx, now)
+	a.True(ok)
+	a.Equal(map[string]interface{}{"error": "context canceled"}, info)
+
+	start := now.Add(-3 * time.Second)
+	deadline := now.Add(-time.Second)
+	ctx = context.WithValue(context.Background(), contextKeyStartTime, start)
+	ctx, cancel = context.WithDeadline(ctx, deadline)
+	defer cancel()
+	info, ok = contextCancellation(ctx, now)
+	a.True(ok)
+	a.Equal("context deadline exceeded", info["error"])
+	a.Equal(deadline.Format(time.RFC3339Nano), info["deadline"])
+	a.Equal("-1s", info["deadline_remaining"])
+	a.Equal("3s", info["elapsed"])
+}
+
+func TestFireWithCancelledContext(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		ctx, cancel := context.WithCancel(WithStartTime(context.Background()))
+		logger.WithContext(ctx).Error(message)
+		packet := <-pch
+		a.NotContains(packet.Extra, extraContextCancellation, "active context should not be reported")
+
+		cancel()
+		logger.WithContext(ctx).Error(message)
+		packet = <-pch
+		info, ok := packet.Extra[extraContextCancellation].(map[string]interface{})
+		a.True(ok, "cancelled context should be reported")
+		a.Equal("context canceled", info["error"])
+		a.Contains(info, "elapsed")
+	})
+}
+
+func TestFireWithContextValues(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		ctx := WithTags(context.Background(), map[string]string{"tenant": "acme", "region": "eu"})
+		ctx = WithTags(ctx, map[string]string{"route": "/users"})
+		ctx = WithUser(ctx, &raven.User{ID: "ctx-user"})
+		ctx = WithTraceID(ctx, "4bf92f3577b34da6a3ce929d0e0e4736")
+
+		logger.WithContext(ctx).Error(message)
+		packet := <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: "tenant", Value: "acme"})
+		a.Contains(packet.Tags, raven.Tag{Key: "region", Value: "eu"})
+		a.Contains(packet.Tags, raven.Tag{Key: "route", Value: "/users"})
+		a.Contains(packet.Tags, raven.Tag{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"})
+		a.Equal("ctx-user", packet.User.ID, "user must be taken from the context")
+
+		logger.WithContext(ctx).WithFields(logrus.Fields{
+			"tags":    raven.Tags{{Key: "tenant", Value: "other"}},
+			"user_id": "field-user",
+		}).Error(message)
+		packet = <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: "tenant", Value: "other"})
+		a.NotContains(packet.Tags, raven.Tag{Key: "tenant", Value: "acme"}, "fields must take precedence over the context")
+		a.Equal("field-user", packet.User.ID, "fields must take precedence over the context")
+	})
+}
+
+func TestFireWithGoroutineLabels(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		ctx := WithTags(context.Background(), map[string]string{"tenant": "acme"})
+		long := strings.Repeat("x", maxTagValueLength+1)
+		pprof.Do(ctx, pprof.Labels("request_id", "42", "tenant", "label", "query", long), func(ctx context.Context) {
+			logger.WithContext(ctx).Error(message)
+		})
+		packet := <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: "request_id", Value: "42"})
+		a.Contains(packet.Tags, raven.Tag{Key: "tenant", Value: "acme"}, "context tags must take precedence over labels")
+		a.NotContains(packet.Tags, raven.Tag{Key: "tenant", Value: "label"})
+		a.Equal(map[string]interface{}{"query": long}, packet.Extra[extraGoroutineLabels])
+	})
+}
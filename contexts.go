@@ -0,0 +1,43 @@
+package logrus_sentry
+
+import (
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// contextsInterface holds the contexts of a packet (trace, runtime...),
+// which raven.Packet has no field for.
+type contextsInterface map[string]interface{}
+
+func (contextsInterface) Class() string { return "contexts" }
+
+// transactionInterface sets the transaction attribute of a packet, which
+// raven.Packet has no field for.
+type transactionInterface string
+
+func (transactionInterface) Class() string { return "transaction" }
+
+// setPacketContext sets the context name of the packet to value.
+func setPacketContext(packet *raven.Packet, name string, value interface{}) {
+	for _, in := range packet.Interfaces {
+		if contexts, ok := in.(contextsInterface); ok {
+			contexts[name] = value
+			return
+		}
+	}
+	packet.Interfaces = append(packet.Interfaces, contextsInterface{name: value})
+}
+
+// getContexts returns the contexts of the contexts field, which are sent
+// verbatim and override those set by the hook.
+func (d *dataField) getContexts() (map[string]interface{}, bool) {
+	switch contexts := d.data[fieldContexts].(type) {
+	case map[string]interface{}:
+		d.omitList[fieldContexts] = struct{}{}
+		return contexts, true
+	case logrus.Fields:
+		d.omitList[fieldContexts] = struct{}{}
+		return contexts, true
+	}
+	return nil, false
+}
@@ -0,0 +1,146 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	defaultCrashLoopWindow = 5 * time.Minute
+
+	// tagCrashLoop is the tag set on the fatal events of a crash loop.
+	tagCrashLoop = "crash_loop"
+	// extraCrashLoopCount is the extra counting the consecutive crashes of
+	// a crash loop.
+	extraCrashLoopCount = "crash_loop_count"
+)
+
+// CrashLoopConfig configures the detection of crash loops, i.e. processes
+// restarted by a supervisor which keep crashing with the same fatal error.
+type CrashLoopConfig struct {
+	// Path is the marker file recording the last fatal event. It must
+	// outlive the process, e.g. on a volume kept across container restarts.
+	Path string
+	// Window is the maximum time between two crashes of a loop.
+	// Defaults to 5 minutes.
+	Window time.Duration
+	// Downgrade is the severity of the fatal events of a crash loop after
+	// the first one. Empty keeps their severity.
+	Downgrade raven.Severity
+	// Aggregate groups the fatal events of a crash loop after the first one
+	// into a single issue per fingerprint.
+	Aggregate bool
+}
+
+// processID identifies the current process in the marker file, so that the
+// marker written by the process itself is not taken for the one of the
+// previous process.
+var processID = newEventID()
+
+type crashLoop struct {
+	config CrashLoopConfig
+	// previous is the last crash of the previous process
+	previous crashMarker
+
+	mu sync.Mutex // serializes the writes of the marker file
+}
+
+// crashMarker is the content of the marker file.
+type crashMarker struct {
+	Process string    `json:"process"`
+	Key     string    `json:"key"`
+	Time    time.Time `json:"time"`
+	Count   int       `json:"count"`
+}
+
+// EnableCrashLoopDetection records the fatal and panic events in the marker
+// file cfg.Path. When a process crashes with the same fingerprint as the
+// previous process within cfg.Window, its event is tagged crash_loop=true,
+// the number of consecutive crashes is sent in the crash_loop_count extra,
+// and the event is downgraded and aggregated as configured. Events without
+// fingerprint are compared by culprit and message.
+//
+// It should be called when the hook is created, before any fatal event.
+func (hook *SentryHook) EnableCrashLoopDetection(cfg CrashLoopConfig) error {
+	if cfg.Path == "" {
+		return errors.New("crash loop marker path is empty")
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultCrashLoopWindow
+	}
+
+	c := &crashLoop{config: cfg}
+	b, err := ioutil.ReadFile(cfg.Path)
+	switch {
+	case err == nil:
+		// a corrupted marker only loses the previous crash
+		_ = json.Unmarshal(b, &c.previous)
+		if c.previous.Process == processID {
+			c.previous = crashMarker{}
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+	hook.crashLoop = c
+	return nil
+}
+
+// apply records the fatal packet, and marks it if it continues the crash
+// loop of the previous process. The fatal and panic events of the current
+// process are only compared with the previous process, not with each other,
+// so that a process counts once in the loop.
+func (c *crashLoop) apply(packet *raven.Packet, key string, now time.Time) {
+	key = hashKey(key)
+
+	count := 1
+	if c.previous.Key == key && now.Sub(c.previous.Time) <= c.config.Window {
+		count = c.previous.Count + 1
+	}
+	c.mu.Lock()
+	c.write(crashMarker{Process: processID, Key: key, Time: now, Count: count})
+	c.mu.Unlock()
+
+	if count == 1 {
+		return
+	}
+	packet.AddTags(map[string]string{tagCrashLoop: "true"})
+	if packet.Extra == nil {
+		packet.Extra = make(map[string]interface{})
+	}
+	packet.Extra[extraCrashLoopCount] = count
+	if c.config.Downgrade != "" {
+		packet.Level = c.config.Downgrade
+	}
+	if c.config.Aggregate {
+		packet.Fingerprint = []string{tagCrashLoop, key}
+	}
+}
+
+// write replaces the marker file with the last crash. c.mu must be held.
+func (c *crashLoop) write(last crashMarker) {
+	b, err := json.Marshal(last)
+	if err != nil {
+		return
+	}
+	_ = writeFileAtomic(c.config.Path, b)
+}
+
+// writeFileAtomic writes b to a temporary file then renames it to path, so
+// that a crash while writing keeps the previous content of path.
+func writeFileAtomic(path string, b []byte) error {
+	tmp := path + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(tmp), 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
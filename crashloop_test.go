@@ -0,0 +1,100 @@
+package logrus_sentry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableCrashLoopDetection(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "crashloop")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	cfg := CrashLoopConfig{
+		Path:      filepath.Join(dir, "state", "crash.json"),
+		Window:    time.Minute,
+		Downgrade: raven.ERROR,
+		Aggregate: true,
+	}
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		// each hook stands for a restarted process
+		crash := func(msg string, at time.Time) *resultPacket {
+			processID = newEventID()
+			hook, err := NewSentryHook(dsn, []logrus.Level{
+				logrus.FatalLevel,
+				logrus.ErrorLevel,
+			})
+			a.NoError(err, "NewSentryHook should be NoError")
+			a.NoError(hook.EnableCrashLoopDetection(cfg))
+
+			a.NoError(hook.Fire(&logrus.Entry{
+				Logger:  getTestLogger(),
+				Level:   logrus.FatalLevel,
+				Message: msg,
+				Time:    at,
+			}))
+			return <-pch
+		}
+
+		now := time.Now()
+		packet := crash("database unreachable", now)
+		a.NotContains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"})
+		a.Equal(raven.FATAL, packet.Level)
+
+		packet = crash("database unreachable", now.Add(30*time.Second))
+		a.Contains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"})
+		a.Equal(float64(2), packet.Extra[extraCrashLoopCount])
+		a.Equal(raven.ERROR, packet.Level, "crash loop events must be downgraded")
+		a.Equal(tagCrashLoop, packet.Fingerprint[0], "crash loop events must be aggregated")
+
+		packet = crash("database unreachable", now.Add(45*time.Second))
+		a.Equal(float64(3), packet.Extra[extraCrashLoopCount])
+
+		packet = crash("config invalid", now.Add(50*time.Second))
+		a.NotContains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"}, "other crashes must not continue the loop")
+
+		packet = crash("config invalid", now.Add(5*time.Minute))
+		a.NotContains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"}, "crashes out of the window must not continue the loop")
+
+		// recovered panics of a single process
+		processID = newEventID()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.PanicLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		a.NoError(hook.EnableCrashLoopDetection(cfg))
+		for i := 0; i < 2; i++ {
+			a.NoError(hook.Fire(&logrus.Entry{
+				Logger:  getTestLogger(),
+				Level:   logrus.PanicLevel,
+				Message: "nil map",
+				Time:    now.Add(6 * time.Minute),
+			}))
+			packet = <-pch
+			a.NotContains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"}, "events of a single process must not make a loop")
+			a.Equal(raven.FATAL, packet.Level)
+		}
+
+		// a hook created again by the same process
+		a.NoError(hook.EnableCrashLoopDetection(cfg))
+		a.NoError(hook.Fire(&logrus.Entry{
+			Logger:  getTestLogger(),
+			Level:   logrus.PanicLevel,
+			Message: "nil map",
+			Time:    now.Add(6 * time.Minute),
+		}))
+		packet = <-pch
+		a.NotContains(packet.Tags, raven.Tag{Key: tagCrashLoop, Value: "true"}, "the marker of the process itself must be ignored")
+	})
+
+	a.Error(new(SentryHook).EnableCrashLoopDetection(CrashLoopConfig{}), "empty path should be an error")
+}
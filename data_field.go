@@ -2,6 +2,7 @@ package logrus_sentry
 
 import (
 	"net/http"
+	"reflect"
 
 	"github.com/musqdp/raven-go"
 	"github.com/sirupsen/logrus"
@@ -15,11 +16,36 @@ const (
 	fieldTags        = "tags"
 	fieldHTTPRequest = "http_request"
 	fieldUser        = "user"
+	fieldUserID      = "user_id"
+	fieldUserName    = "user_name"
+	fieldUserEmail   = "user_email"
+	fieldUserIP      = "user_ip"
+	fieldContexts    = "contexts"
+	fieldTransaction = "transaction"
 )
 
+// reservedFields are the field keys with a special meaning for the hook.
+var reservedFields = []string{
+	logrus.ErrorKey,
+	fieldEventID,
+	fieldFingerprint,
+	fieldLogger,
+	fieldServerName,
+	fieldTags,
+	fieldHTTPRequest,
+	fieldUser,
+	fieldAttachments,
+	fieldContexts,
+	fieldTransaction,
+	fieldCmd,
+	fieldFinalizers,
+}
+
 type dataField struct {
 	data     logrus.Fields
 	omitList map[string]struct{}
+	// attachments holds the extras moved to attachments
+	attachments []Attachment
 }
 
 func newDataField(data logrus.Fields) *dataField {
@@ -38,6 +64,32 @@ func (d *dataField) isOmit(key string) bool {
 	return ok
 }
 
+// unusedReservedFields returns the reserved fields which are present but
+// were not consumed, i.e. whose values have an unsupported type or format.
+// Nil values are not reported, as they stand for missing values.
+func (d *dataField) unusedReservedFields() []string {
+	var keys []string
+	for _, key := range reservedFields {
+		if v, ok := d.data[key]; ok && !d.isOmit(key) && !isNil(v) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// isNil reports whether v is nil, or a nil pointer, map, slice, function,
+// channel or interface.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
 func (d *dataField) getLogger() (string, bool) {
 	if logger, ok := d.data[fieldLogger].(string); ok {
 		d.omitList[fieldLogger] = struct{}{}
@@ -62,6 +114,14 @@ func (d *dataField) getTags() (raven.Tags, bool) {
 	return nil, false
 }
 
+func (d *dataField) getTransaction() (string, bool) {
+	if transaction, ok := d.data[fieldTransaction].(string); ok && transaction != "" {
+		d.omitList[fieldTransaction] = struct{}{}
+		return transaction, true
+	}
+	return "", false
+}
+
 func (d *dataField) getFingerprint() ([]string, bool) {
 	if fingerprint, ok := d.data[fieldFingerprint].([]string); ok {
 		d.omitList[fieldFingerprint] = struct{}{}
@@ -71,7 +131,7 @@ func (d *dataField) getFingerprint() ([]string, bool) {
 }
 
 func (d *dataField) getError() (error, bool) {
-	if err, ok := d.data[logrus.ErrorKey].(error); ok {
+	if err, ok := d.data[logrus.ErrorKey].(error); ok && !isNil(err) {
 		d.omitList[logrus.ErrorKey] = struct{}{}
 		return err, true
 	}
@@ -79,11 +139,11 @@ func (d *dataField) getError() (error, bool) {
 }
 
 func (d *dataField) getHTTPRequest() (*raven.Http, bool) {
-	if req, ok := d.data[fieldHTTPRequest].(*http.Request); ok {
+	if req, ok := d.data[fieldHTTPRequest].(*http.Request); ok && req != nil {
 		d.omitList[fieldHTTPRequest] = struct{}{}
 		return raven.NewHttp(req), true
 	}
-	if req, ok := d.data[fieldHTTPRequest].(*raven.Http); ok {
+	if req, ok := d.data[fieldHTTPRequest].(*raven.Http); ok && req != nil {
 		d.omitList[fieldHTTPRequest] = struct{}{}
 		return req, true
 	}
@@ -106,28 +166,73 @@ func (d *dataField) getEventID() (string, bool) {
 	return uuid.noDashString(), true
 }
 
+// UserFieldMapping holds the field names from which the Sentry user
+// interface is built when the entry has no `user` field.
+// Empty names fall back to the default field names.
+type UserFieldMapping struct {
+	ID       string
+	Username string
+	Email    string
+	IP       string
+}
+
+var defaultUserFieldMapping = UserFieldMapping{
+	ID:       fieldUserID,
+	Username: fieldUserName,
+	Email:    fieldUserEmail,
+	IP:       fieldUserIP,
+}
+
+func (m UserFieldMapping) withDefaults() UserFieldMapping {
+	if m.ID == "" {
+		m.ID = defaultUserFieldMapping.ID
+	}
+	if m.Username == "" {
+		m.Username = defaultUserFieldMapping.Username
+	}
+	if m.Email == "" {
+		m.Email = defaultUserFieldMapping.Email
+	}
+	if m.IP == "" {
+		m.IP = defaultUserFieldMapping.IP
+	}
+	return m
+}
+
 func (d *dataField) getUser() (*raven.User, bool) {
+	return d.getUserWithMapping(defaultUserFieldMapping)
+}
+
+func (d *dataField) getUserWithMapping(m UserFieldMapping) (*raven.User, bool) {
 	data := d.data
 	if v, ok := data[fieldUser]; ok {
 		switch val := v.(type) {
 		case *raven.User:
-			d.omitList[fieldUser] = struct{}{}
-			return val, true
+			if val != nil {
+				d.omitList[fieldUser] = struct{}{}
+				return val, true
+			}
 		case raven.User:
 			d.omitList[fieldUser] = struct{}{}
 			return &val, true
 		}
 	}
 
-	username, _ := data["user_name"].(string)
-	email, _ := data["user_email"].(string)
-	id, _ := data["user_id"].(string)
-	ip, _ := data["user_ip"].(string)
+	m = m.withDefaults()
+	username, _ := data[m.Username].(string)
+	email, _ := data[m.Email].(string)
+	id, _ := data[m.ID].(string)
+	ip, _ := data[m.IP].(string)
 
 	if username == "" && email == "" && id == "" && ip == "" {
 		return nil, false
 	}
 
+	for _, key := range []string{m.ID, m.Username, m.Email, m.IP} {
+		if _, ok := data[key].(string); ok {
+			d.omitList[key] = struct{}{}
+		}
+	}
 	return &raven.User{
 		ID:       id,
 		Username: username,
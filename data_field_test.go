@@ -235,6 +235,8 @@ func TestGetError(t *testing.T) {
 		{"error", 1, false, "invalid value type"},
 		{"error", true, false, "invalid value type"},
 		{"error", struct{}{}, false, "invalid value type"},
+		{"error", nil, false, "nil error"},
+		{"error", (*nilError)(nil), false, "typed nil error"},
 	}
 
 	for _, tt := range tests {
@@ -414,3 +416,119 @@ func TestGetUserFromString(t *testing.T) {
 		}
 	}
 }
+
+func TestGetUserWithMapping(t *testing.T) {
+	a := assert.New(t)
+
+	mapping := UserFieldMapping{
+		ID:    "uid",
+		Email: "mail",
+	}
+
+	tests := []struct {
+		data        map[string]interface{}
+		expected    *raven.User
+		omitted     []string
+		description string
+	}{
+		{map[string]interface{}{
+			"uid":  "A0001",
+			"mail": "example@example.com",
+		}, &raven.User{ID: "A0001", Email: "example@example.com"}, []string{"uid", "mail"}, "mapped fields"},
+		{map[string]interface{}{
+			"uid":       "A0001",
+			"user_name": "name",
+		}, &raven.User{ID: "A0001", Username: "name"}, []string{"uid", "user_name"}, "unmapped fields use defaults"},
+		{map[string]interface{}{
+			"user_id": "A0001",
+		}, nil, nil, "default key replaced by mapping"},
+		{map[string]interface{}{
+			"uid": 1,
+		}, nil, nil, "invalid type"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		df := newDataField(logrus.Fields(tt.data))
+		user, ok := df.getUserWithMapping(mapping)
+		a.Equal(tt.expected != nil, ok, target)
+		a.Equal(tt.expected, user, target)
+		for _, key := range tt.omitted {
+			a.True(df.isOmit(key), "`%s` should be in omitList", key)
+		}
+		if !ok {
+			for key := range tt.data {
+				a.False(df.isOmit(key), "`%s` should not be in omitList", key)
+			}
+		}
+	}
+}
+
+func TestGetContexts(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		key         string
+		value       interface{}
+		expected    bool
+		description string
+	}{
+		{"contexts", map[string]interface{}{"app": map[string]interface{}{"app_name": "api"}}, true, "valid contexts"},
+		{"contexts", logrus.Fields{"app": map[string]interface{}{"app_name": "api"}}, true, "valid contexts"},
+		{"not_contexts", map[string]interface{}{}, false, "invalid key"},
+		{"contexts", map[string]string{}, false, "invalid value type"},
+		{"contexts", "test_contexts", false, "invalid value type"},
+		{"contexts", 1, false, "invalid value type"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		fields := logrus.Fields{}
+		fields[tt.key] = tt.value
+
+		df := newDataField(fields)
+		contexts, ok := df.getContexts()
+		a.Equal(tt.expected, ok, target)
+		if ok {
+			a.EqualValues(tt.value, contexts, target)
+			a.True(df.isOmit("contexts"), "`contexts` should be in omitList")
+		} else {
+			a.False(df.isOmit("contexts"), "`contexts` should not be in omitList")
+		}
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		key         string
+		value       interface{}
+		expected    bool
+		description string
+	}{
+		{"transaction", "GET /api/v1/users/:id", true, "valid transaction"},
+		{"transaction", "", false, "empty transaction"},
+		{"not_transaction", "worker.ProcessPayment", false, "invalid key"},
+		{"transaction", 1, false, "invalid value type"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		fields := logrus.Fields{}
+		fields[tt.key] = tt.value
+
+		df := newDataField(fields)
+		transaction, ok := df.getTransaction()
+		a.Equal(tt.expected, ok, target)
+		if ok {
+			a.Equal(tt.value, transaction, target)
+			a.True(df.isOmit("transaction"), "`transaction` should be in omitList")
+		} else {
+			a.False(df.isOmit("transaction"), "`transaction` should not be in omitList")
+		}
+	}
+}
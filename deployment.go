@@ -0,0 +1,139 @@
+package logrus_sentry
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	tagDeployID      = "deploy_id"
+	tagDeployTrack   = "deploy_track"
+	tagDeployRollout = "deploy_rollout"
+
+	// deploymentRetryInterval is the time between the queries of a failing
+	// deployment provider.
+	deploymentRetryInterval = 10 * time.Second
+)
+
+// Deployment describes the rollout the process belongs to.
+type Deployment struct {
+	// ID identifies the deployment, e.g. a rollout or replica set name.
+	ID string
+	// Track is the track of the process, e.g. "canary" or "stable".
+	Track string
+	// Rollout is the percentage of the fleet running the deployment.
+	// Zero means unknown.
+	Rollout float64
+}
+
+// DeploymentProvider returns the deployment of the process, e.g. from the
+// orchestrator API or the pod labels, without the hook depending on them.
+type DeploymentProvider interface {
+	Deployment() (Deployment, error)
+}
+
+// DeploymentProviderFunc adapts a function to a DeploymentProvider.
+type DeploymentProviderFunc func() (Deployment, error)
+
+// Deployment returns fn().
+func (fn DeploymentProviderFunc) Deployment() (Deployment, error) {
+	return fn()
+}
+
+// deploymentCache queries the provider lazily and caches its deployment.
+type deploymentCache struct {
+	provider DeploymentProvider
+	ttl      time.Duration
+
+	mu         sync.Mutex
+	deployment Deployment
+	// next is the time of the next query, unless ttl is zero and a query
+	// succeeded
+	next       time.Time
+	queried    bool
+	succeeded  bool
+	refreshing bool
+}
+
+// SetDeploymentProvider tags the events with the deployment of the process:
+// its ID in deploy_id, its track in deploy_track and its rollout percentage
+// in deploy_rollout, so that canary regressions can be told apart from the
+// stable fleet. The provider is queried on the first event, then in the
+// background at most once per ttl, so that a slow provider does not delay
+// the events; a zero ttl caches the deployment forever. When it fails, the
+// last deployment is kept and the provider is queried again every 10
+// seconds until it succeeds. Tags set from the entry's fields or context
+// win.
+func (hook *SentryHook) SetDeploymentProvider(provider DeploymentProvider, ttl time.Duration) {
+	if provider == nil {
+		hook.deployment = nil
+		return
+	}
+	hook.deployment = &deploymentCache{
+		provider: provider,
+		ttl:      ttl,
+	}
+}
+
+// get returns the cached deployment, and queries the provider if it is due:
+// the first time on the caller, then in the background. The provider is
+// never called with c.mu held.
+func (c *deploymentCache) get(now time.Time) Deployment {
+	c.mu.Lock()
+	if c.refreshing || (c.succeeded && c.ttl <= 0) || now.Before(c.next) {
+		defer c.mu.Unlock()
+		return c.deployment
+	}
+	c.refreshing = true
+	first := !c.queried
+	c.queried = true
+	c.mu.Unlock()
+
+	if first {
+		return c.refresh(now)
+	}
+	go c.refresh(now)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deployment
+}
+
+// refresh queries the provider and returns the cached deployment.
+func (c *deploymentCache) refresh(now time.Time) Deployment {
+	deployment, err := c.provider.Deployment()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	if err != nil {
+		c.next = now.Add(deploymentRetryInterval)
+		return c.deployment
+	}
+	c.deployment = deployment
+	c.succeeded = true
+	c.next = now.Add(c.ttl)
+	return deployment
+}
+
+// applyDeployment tags the packet with the deployment of the process.
+func (hook *SentryHook) applyDeployment(packet *raven.Packet, now time.Time) {
+	d := hook.deployment.get(now)
+
+	tags := make(map[string]string)
+	if d.ID != "" {
+		tags[tagDeployID] = d.ID
+	}
+	if d.Track != "" {
+		tags[tagDeployTrack] = d.Track
+	}
+	if d.Rollout != 0 {
+		tags[tagDeployRollout] = strconv.FormatFloat(d.Rollout, 'f', -1, 64)
+	}
+	for _, tag := range packet.Tags {
+		delete(tags, tag.Key)
+	}
+	packet.AddTags(tags)
+}
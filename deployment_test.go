@@ -0,0 +1,83 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDeploymentProvider(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		queries := 0
+		hook.SetDeploymentProvider(DeploymentProviderFunc(func() (Deployment, error) {
+			queries++
+			return Deployment{ID: "api-7d9f", Track: "canary", Rollout: 12.5}, nil
+		}), time.Hour)
+		a.Equal(0, queries, "the provider must be queried lazily")
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: tagDeployID, Value: "api-7d9f"})
+		a.Contains(packet.Tags, raven.Tag{Key: tagDeployTrack, Value: "canary"})
+		a.Contains(packet.Tags, raven.Tag{Key: tagDeployRollout, Value: "12.5"})
+
+		logger.WithField("tags", raven.Tags{{Key: tagDeployTrack, Value: "stable"}}).Error(message)
+		packet = <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: tagDeployTrack, Value: "stable"}, "fields must take precedence over the deployment")
+		a.NotContains(packet.Tags, raven.Tag{Key: tagDeployTrack, Value: "canary"})
+		a.Equal(1, queries, "the deployment must be cached")
+	})
+}
+
+func TestDeploymentCache(t *testing.T) {
+	a := assert.New(t)
+
+	var deployment Deployment
+	var err error
+	provider := DeploymentProviderFunc(func() (Deployment, error) { return deployment, err })
+	c := &deploymentCache{provider: provider, ttl: time.Minute}
+	// get refreshes the deployment of c and waits for the refresh
+	get := func(c *deploymentCache, now time.Time) string {
+		c.get(now)
+		for {
+			c.mu.Lock()
+			refreshing, id := c.refreshing, c.deployment.ID
+			c.mu.Unlock()
+			if !refreshing {
+				return id
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	now := time.Now()
+
+	deployment = Deployment{ID: "v1"}
+	a.Equal("v1", c.get(now).ID, "the first query must be done on the caller")
+	deployment = Deployment{ID: "v2"}
+	a.Equal("v1", get(c, now.Add(30*time.Second)))
+	a.Equal("v2", get(c, now.Add(time.Minute)), "the deployment must be queried once expired")
+
+	err = errors.New("unavailable")
+	a.Equal("v2", get(c, now.Add(2*time.Minute)), "the last deployment must be kept on failure")
+
+	c = &deploymentCache{provider: provider}
+	a.Equal("", c.get(now).ID)
+	err = nil
+	a.Equal("", get(c, now.Add(time.Second)), "a failing provider must not be queried on every event")
+	a.Equal("v2", get(c, now.Add(deploymentRetryInterval)), "a failed query must be retried without ttl")
+	deployment = Deployment{ID: "v3"}
+	a.Equal("v2", get(c, now.Add(time.Hour)), "the deployment must be cached forever without ttl")
+}
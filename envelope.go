@@ -0,0 +1,103 @@
+package logrus_sentry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+// sendEnvelope sends the event of packet and its attachments in an envelope.
+func sendEnvelope(httpClient *http.Client, url, authHeader string, packet *raven.Packet, attachments []Attachment) error {
+	body, err := envelope(packet, attachments)
+	if err != nil {
+		return fmt.Errorf("error serializing packet: %v", err)
+	}
+	req, err := http.NewRequest("POST", envelopeURL(url), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("can't create new request: %v", err)
+	}
+	req.Header.Set("X-Sentry-Auth", authHeader)
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	// the body must be read for the connection to be reused
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("raven: got http status %d - x-sentry-error: %s", res.StatusCode, res.Header.Get("X-Sentry-Error"))
+	}
+	return nil
+}
+
+// envelopeURL returns the envelope endpoint of a store endpoint.
+func envelopeURL(storeURL string) string {
+	if strings.HasSuffix(storeURL, "/store/") {
+		return strings.TrimSuffix(storeURL, "/store/") + "/envelope/"
+	}
+	return storeURL
+}
+
+// envelope returns the envelope holding the event of packet and its
+// attachments.
+func envelope(packet *raven.Packet, attachments []Attachment) ([]byte, error) {
+	payload, err := packet.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeEnvelopeHeader(&buf, map[string]interface{}{
+		"event_id": packet.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339),
+	}); err != nil {
+		return nil, err
+	}
+	if err := writeEnvelopeItem(&buf, map[string]interface{}{
+		"type":   "event",
+		"length": len(payload),
+	}, payload); err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		header := map[string]interface{}{
+			"type":     "attachment",
+			"length":   len(a.Payload),
+			"filename": a.Filename,
+		}
+		if a.ContentType != "" {
+			header["content_type"] = a.ContentType
+		}
+		if err := writeEnvelopeItem(&buf, header, a.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func writeEnvelopeHeader(buf *bytes.Buffer, header map[string]interface{}) error {
+	b, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return nil
+}
+
+func writeEnvelopeItem(buf *bytes.Buffer, header map[string]interface{}, payload []byte) error {
+	if err := writeEnvelopeHeader(buf, header); err != nil {
+		return err
+	}
+	buf.Write(payload)
+	buf.WriteByte('\n')
+	return nil
+}
@@ -0,0 +1,85 @@
+package logrus_sentry
+
+import (
+	"html"
+	"strings"
+
+	"github.com/musqdp/raven-go"
+)
+
+// Escaping is a set of escapings applied to the user controlled text of the
+// events.
+type Escaping int
+
+const (
+	// EscapeHTML escapes the characters interpreted by HTML: & < > ' ".
+	EscapeHTML Escaping = 1 << iota
+	// EscapeMarkdown escapes the characters of the inline markdown syntax
+	// with a backslash: \ ` * _ ~ [ ] |.
+	EscapeMarkdown
+)
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`~`, `\~`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`|`, `\|`,
+)
+
+// SetEscaping escapes the messages, the culprits, the error messages and the
+// string extras set from fields of the events, so that user controlled input
+// does not mangle the issue titles in the sentry UI or in the chat
+// integrations receiving them. The markdown escaping is applied before the
+// HTML one. Zero disables it.
+func (hook *SentryHook) SetEscaping(escaping Escaping) {
+	hook.escaping = escaping
+}
+
+// SetDSNEscaping sets the escaping of the events sent to dsn, the DSN of a
+// route or a DSN returned by the resolver of WithDSNResolver, instead of the
+// one set by SetEscaping. Zero disables the escaping for dsn.
+func (hook *SentryHook) SetDSNEscaping(dsn string, escaping Escaping) {
+	if hook.dsnEscaping == nil {
+		hook.dsnEscaping = make(map[string]Escaping)
+	}
+	hook.dsnEscaping[dsn] = escaping
+}
+
+// escapingOf returns the escaping of the events sent to d.
+func (hook *SentryHook) escapingOf(d destination) Escaping {
+	dsn := d.dsn
+	if d.route != nil {
+		dsn = d.route.dsn
+	}
+	if escaping, ok := hook.dsnEscaping[dsn]; ok && dsn != "" {
+		return escaping
+	}
+	return hook.escaping
+}
+
+// escape returns s escaped.
+func (e Escaping) escape(s string) string {
+	if e&EscapeMarkdown != 0 {
+		s = markdownEscaper.Replace(s)
+	}
+	if e&EscapeHTML != 0 {
+		s = html.EscapeString(s)
+	}
+	return s
+}
+
+// escapePacket escapes the message, the culprit and the error messages of
+// packet.
+func (e Escaping) escapePacket(packet *raven.Packet) {
+	packet.Message = e.escape(packet.Message)
+	packet.Culprit = e.escape(packet.Culprit)
+	for _, in := range packet.Interfaces {
+		if exc, ok := in.(*raven.Exception); ok {
+			exc.Value = e.escape(exc.Value)
+		}
+	}
+}
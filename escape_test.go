@@ -0,0 +1,91 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscape(t *testing.T) {
+	a := assert.New(t)
+
+	s := `<b>*bold*</b> _under_ [link](x) a|b ~s~ \ & "q"`
+	a.Equal(s, Escaping(0).escape(s))
+	a.Equal(`&lt;b&gt;*bold*&lt;/b&gt; _under_ [link](x) a|b ~s~ \ &amp; &#34;q&#34;`, EscapeHTML.escape(s))
+	a.Equal(`<b>\*bold\*</b> \_under\_ \[link\](x) a\|b \~s\~ \\ & "q"`, EscapeMarkdown.escape(s))
+	a.Equal(`&lt;b&gt;\*bold\*&lt;/b&gt; \_under\_ \[link\](x) a\|b \~s\~ \\ &amp; &#34;q&#34;`, (EscapeHTML | EscapeMarkdown).escape(s))
+}
+
+func TestSetEscaping(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.StacktraceConfiguration.Enable = true
+		hook.SetEscaping(EscapeHTML | EscapeMarkdown)
+		hook.AddExtraFilter("password", func(interface{}) interface{} { return "[Filtered]" })
+		hook.SetLargeExtras(10, LargeExtraGzip)
+		logger.Hooks.Add(hook)
+
+		logger.WithError(errors.New("no user <script>")).WithFields(logrus.Fields{
+			"query":    "name = '*admin*'",
+			"amount":   42,
+			"password": "hunter2",
+			"handler":  strings.Repeat("*", 100),
+		}).Error("login failed for **<img src=x>**")
+		packet := <-pch
+		a.Equal(`login failed for \*\*&lt;img src=x&gt;\*\*`, packet.Message)
+		a.Equal("no user &lt;script&gt;", packet.Exception.Value)
+		a.Equal(`name = &#39;\*admin\*&#39;`, packet.Extra["query"])
+		a.Equal(float64(42), packet.Extra["amount"], "only strings should be escaped")
+		a.Equal("[Filtered]", packet.Extra["password"], "filtered values should not be escaped")
+		decoded, err := DecodeExtra(packet.Extra["handler"].(string))
+		a.NoError(err)
+		a.Equal(strings.Repeat("*", 100), decoded, "encoded values should be decoded to the original")
+
+		hook.StacktraceConfiguration.Enable = false
+		logger.WithError(errors.New("no user <script>")).Error(message)
+		packet = <-pch
+		a.Equal("no user &lt;script&gt;", packet.Culprit)
+	})
+}
+
+func TestSetDSNEscaping(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		WithTestDSN(t, func(routeDSN string, routePch <-chan *resultPacket) {
+			logger := getTestLogger()
+			hook, err := NewRoutingSentryHook(dsn, []Route{{
+				DSN: routeDSN,
+				Match: func(entry *logrus.Entry) bool {
+					_, ok := entry.Data["team"]
+					return ok
+				},
+			}}, []logrus.Level{
+				logrus.ErrorLevel,
+			})
+			a.NoError(err, "NewRoutingSentryHook should be NoError")
+			hook.SetEscaping(EscapeHTML)
+			hook.SetDSNEscaping(routeDSN, EscapeMarkdown)
+			logger.Hooks.Add(hook)
+
+			logger.WithField("query", "<*>").Error("<b>*bold*</b>")
+			packet := <-pch
+			a.Equal("&lt;b&gt;*bold*&lt;/b&gt;", packet.Message)
+			a.Equal("&lt;*&gt;", packet.Extra["query"])
+
+			logger.WithFields(logrus.Fields{"team": "payments", "query": "<*>"}).Error("<b>*bold*</b>")
+			packet = <-routePch
+			a.Equal(`<b>\*bold\*</b>`, packet.Message, "the escaping of the route should be used")
+			a.Equal(`<\*>`, packet.Extra["query"])
+		})
+	})
+}
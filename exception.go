@@ -0,0 +1,70 @@
+package logrus_sentry
+
+import (
+	"strings"
+)
+
+// nextCause returns the error wrapped by err, following both pkg/errors
+// causers and Go 1.13 wrappers, or nil.
+func nextCause(err error) error {
+	var cause error
+	switch e := err.(type) {
+	case interface{ Cause() error }:
+		cause = e.Cause()
+	case interface{ Unwrap() error }:
+		cause = e.Unwrap()
+	}
+	if isNil(cause) {
+		return nil
+	}
+	return cause
+}
+
+// causeOf returns the innermost error of the pkg/errors causer chain of err,
+// like errors.Cause, but stops before nil and typed nil causes.
+func causeOf(err error) error {
+	for {
+		c, ok := err.(interface{ Cause() error })
+		if !ok {
+			return err
+		}
+		cause := c.Cause()
+		if isNil(cause) {
+			return err
+		}
+		err = cause
+	}
+}
+
+// rootCause returns the innermost error of the chain of err.
+func rootCause(err error) error {
+	for {
+		cause := nextCause(err)
+		if cause == nil {
+			return err
+		}
+		err = cause
+	}
+}
+
+// prettyExceptionValue composes the exception value of an error chain from
+// the message of the outermost wrapper and the one of the root cause, e.g.
+// "handle request: ... caused by connection refused", as the intermediate
+// wrap messages would otherwise dominate the title of the issue.
+func prettyExceptionValue(err, root error) string {
+	msg := err.Error()
+	// wrappers adding a stack trace have the message of their cause
+	var next error
+	for e := nextCause(err); e != nil; e = nextCause(e) {
+		if e.Error() != msg {
+			next = e
+			break
+		}
+	}
+	if next == nil || next == root || next.Error() == root.Error() {
+		return msg
+	}
+
+	outer := strings.TrimSuffix(msg, ": "+next.Error())
+	return outer + ": ... caused by " + root.Error()
+}
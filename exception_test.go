@@ -0,0 +1,68 @@
+package logrus_sentry
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type rootError struct{}
+
+func (rootError) Error() string { return "connection refused" }
+
+// unwrapError is a Go 1.13 style wrapper.
+type unwrapError struct {
+	msg string
+	err error
+}
+
+func (e unwrapError) Error() string { return e.msg + ": " + e.err.Error() }
+
+func (e unwrapError) Unwrap() error { return e.err }
+
+func TestPrettyExceptionValue(t *testing.T) {
+	a := assert.New(t)
+
+	root := rootError{}
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{root, "connection refused"},
+		{errors.Wrap(root, "query users"), "query users: connection refused"},
+		{errors.Wrap(errors.Wrap(root, "query users"), "handle request"), "handle request: ... caused by connection refused"},
+		{unwrapError{"handle request", unwrapError{"query users", root}}, "handle request: ... caused by connection refused"},
+		{errors.WithStack(errors.Wrap(errors.WithMessage(root, "query users"), "handle request")), "handle request: ... caused by connection refused"},
+	}
+
+	for _, tt := range tests {
+		cause := rootCause(tt.err)
+		a.Equal(root, cause, tt.err.Error())
+		a.Equal(tt.expected, prettyExceptionValue(tt.err, cause), tt.err.Error())
+	}
+}
+
+func TestPrettyExceptionTitles(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.StacktraceConfiguration.Enable = true
+		hook.StacktraceConfiguration.PrettyExceptionTitles = true
+		logger.Hooks.Add(hook)
+
+		err = unwrapError{"handle request", errors.Wrap(rootError{}, "query users")}
+		logger.WithError(err).Error(message)
+
+		packet := <-pch
+		a.Equal("logrus_sentry.rootError", packet.Exception.Type, "type should be the one of the root cause")
+		a.Equal("handle request: ... caused by connection refused", packet.Exception.Value)
+		a.Equal(err.Error(), packet.Culprit)
+	})
+}
@@ -0,0 +1,213 @@
+package logrus_sentry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fieldFinalizers is the field holding the Finalizers of an entry.
+const fieldFinalizers = "sentry_finalizers"
+
+// The reasons of the Dropped deliveries.
+const (
+	DropThreshold   = "threshold"
+	DropStrict      = "strict"
+	DropThrottle    = "throttle"
+	DropOverflow    = "overflow"
+	DropPause       = "pause"
+	DropQueue       = "queue"
+	DropSampling    = "sampling"
+	DropIgnoreRules = "ignore_rules"
+)
+
+// DeliveryStatus is the outcome of the delivery of an event.
+type DeliveryStatus int
+
+const (
+	// Delivered means that the transport sent the event.
+	Delivered DeliveryStatus = iota
+	// DeliveryFailed means that the transport failed to send the event.
+	DeliveryFailed
+	// Dropped means that the event was not sent at all.
+	Dropped
+)
+
+func (s DeliveryStatus) String() string {
+	switch s {
+	case Delivered:
+		return "delivered"
+	case DeliveryFailed:
+		return "failed"
+	case Dropped:
+		return "dropped"
+	}
+	return "unknown"
+}
+
+// Delivery describes the outcome of the delivery of the event of an entry.
+type Delivery struct {
+	Status DeliveryStatus
+	// EventID is the ID of the event, set unless it was dropped.
+	EventID string
+	// Err is the send error of a failed delivery, or the error of the strict
+	// mode for an event it dropped.
+	Err error
+	// Reason is why the event was dropped, one of the Drop constants.
+	Reason string
+}
+
+// Finalizer is called once with the outcome of the delivery of the event of
+// an entry, e.g. to record the event ID in a job record, or to count an error
+// only if it was actually reported. It is not called while the hook holds its
+// lock, so it may log.
+type Finalizer func(Delivery)
+
+// Finalizers are the finalizers of an entry, set in the sentry_finalizers
+// field. It is a slice because logrus discards func fields.
+type Finalizers []Finalizer
+
+type finalizersKey struct{}
+
+// WithFinalizer returns a copy of ctx carrying fn, in addition to the
+// finalizers ctx already carries. It is called with the outcome of the
+// delivery of every event fired with the context.
+func WithFinalizer(ctx context.Context, fn Finalizer) context.Context {
+	prev := finalizersFromContext(ctx)
+	fins := make(Finalizers, 0, len(prev)+1)
+	fins = append(fins, prev...)
+	fins = append(fins, fn)
+	return context.WithValue(ctx, finalizersKey{}, fins)
+}
+
+func finalizersFromContext(ctx context.Context) Finalizers {
+	if ctx == nil {
+		return nil
+	}
+	fins, _ := ctx.Value(finalizersKey{}).(Finalizers)
+	return fins
+}
+
+// getFinalizers returns the finalizers of the sentry_finalizers field, then
+// those carried by the context of the entry.
+func (d *dataField) getFinalizers(entry *logrus.Entry) Finalizers {
+	var fins Finalizers
+	switch f := d.data[fieldFinalizers].(type) {
+	case Finalizers:
+		d.omitList[fieldFinalizers] = struct{}{}
+		fins = append(fins, f...)
+	case []Finalizer:
+		d.omitList[fieldFinalizers] = struct{}{}
+		fins = append(fins, f...)
+	}
+	return append(fins, finalizersFromContext(entry.Context)...)
+}
+
+// finish calls the finalizers with d.
+func (fins Finalizers) finish(d Delivery) {
+	for _, fn := range fins {
+		if fn != nil {
+			fn(d)
+		}
+	}
+}
+
+// dropped returns the Delivery of an event which was not sent.
+func dropped(reason string, err error) Delivery {
+	return Delivery{Status: Dropped, Reason: reason, Err: err}
+}
+
+// delivered returns the Delivery of an event sent with the send error err.
+func delivered(eventID string, err error) Delivery {
+	d := Delivery{Status: Delivered, EventID: eventID}
+	if err != nil {
+		d.Status = DeliveryFailed
+		d.Err = err
+	}
+	return d
+}
+
+// outcome is the outcome of an event known when Fire returns. The finalizers
+// are called with it once hook.mu is released, since they may log.
+type outcome struct {
+	fins Finalizers
+	d    *Delivery
+}
+
+func (o *outcome) drop(reason string, err error) {
+	d := dropped(reason, err)
+	o.d = &d
+}
+
+func (o *outcome) deliver(eventID string, err error) {
+	d := delivered(eventID, err)
+	o.d = &d
+}
+
+func (o *outcome) finish() {
+	if o.d != nil {
+		o.fins.finish(*o.d)
+	}
+}
+
+// finalizerGroup counts the finalizers running in the background. Unlike
+// hook.wg, Flush waits for it without holding hook.mu, so that the
+// finalizers may log.
+type finalizerGroup struct {
+	mu   sync.Mutex
+	idle *sync.Cond
+	n    int
+}
+
+func (g *finalizerGroup) add() {
+	g.mu.Lock()
+	g.n++
+	g.mu.Unlock()
+}
+
+func (g *finalizerGroup) done() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.n--
+	if g.n == 0 && g.idle != nil {
+		g.idle.Broadcast()
+	}
+}
+
+func (g *finalizerGroup) wait() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.idle == nil {
+		g.idle = sync.NewCond(&g.mu)
+	}
+	for g.n > 0 {
+		g.idle.Wait()
+	}
+}
+
+// finalizeAfter calls done, which releases the wait group the caller is
+// counted in, then the finalizers with d.
+func (hook *SentryHook) finalizeAfter(fins Finalizers, d Delivery, done func()) {
+	if len(fins) == 0 {
+		done()
+		return
+	}
+	hook.finalizing.add()
+	defer hook.finalizing.done()
+	done()
+	fins.finish(d)
+}
+
+// finalizeLater calls the finalizers in the background once the result of
+// the send of a synchronous event Fire did not wait for is known.
+func (hook *SentryHook) finalizeLater(fins Finalizers, eventID string, errCh chan error) {
+	if len(fins) == 0 {
+		return
+	}
+	hook.finalizing.add()
+	go func() {
+		defer hook.finalizing.done()
+		fins.finish(delivered(eventID, <-errCh))
+	}()
+}
@@ -0,0 +1,178 @@
+package logrus_sentry
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinalizerField(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		var deliveries []Delivery
+		logger.WithField(fieldFinalizers, Finalizers{func(d Delivery) {
+			deliveries = append(deliveries, d)
+		}}).Error(message)
+		packet := <-pch
+		a.NotContains(packet.Extra, fieldFinalizers, "the finalizers should not be sent")
+		if a.Len(deliveries, 1, "the finalizer should be called once") {
+			a.Equal(Delivered, deliveries[0].Status)
+			a.Equal(packet.EventID, deliveries[0].EventID)
+			a.NoError(deliveries[0].Err)
+		}
+	})
+}
+
+func TestFinalizerFailed(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusBadRequest)
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	var delivery Delivery
+	logger.WithField(fieldFinalizers, Finalizers{func(d Delivery) {
+		delivery = d
+	}}).Error(message)
+	a.Equal(DeliveryFailed, delivery.Status)
+	a.NotEmpty(delivery.EventID)
+	a.Error(delivery.Err)
+}
+
+func TestFinalizerContext(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+	})
+	defer s.Close()
+
+	hook, err := NewAsyncSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewAsyncSentryHook should be NoError")
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	var mu sync.Mutex
+	var calls []string
+	ctx := WithFinalizer(context.Background(), func(d Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, "first "+d.Status.String())
+	})
+	ctx = WithFinalizer(ctx, func(d Delivery) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, "second "+d.Status.String())
+	})
+	logger.WithContext(ctx).Error(message)
+	hook.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	a.Equal([]string{"first delivered", "second delivered"}, calls)
+}
+
+func TestFinalizerDropped(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.NoError(hook.SetIgnoreErrors("^ignored"))
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	var delivery Delivery
+	calls := 0
+	fins := Finalizers{func(d Delivery) {
+		delivery = d
+		calls++
+	}}
+	logger.WithField(fieldFinalizers, fins).Error("ignored " + message)
+	a.Equal(1, calls, "the finalizer should be called once")
+	a.Equal(Dropped, delivery.Status)
+	a.Equal(DropIgnoreRules, delivery.Reason)
+	a.Empty(delivery.EventID)
+
+	hook.PauseSends()
+	hook.pause.events = make([]pendingEvent, maxPausedEvents)
+	logger.WithField(fieldFinalizers, fins).Error(message)
+	a.Equal(2, calls, "the finalizer should be called once")
+	a.Equal(Dropped, delivery.Status)
+	a.Equal(DropPause, delivery.Reason)
+}
+
+func TestFinalizerLogs(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+		logrus.WarnLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.SetThrottle(1, time.Hour)
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	fins := Finalizers{func(d Delivery) {
+		if d.Status != Dropped {
+			return
+		}
+		// the flush waits for the lock of the hook meanwhile
+		flushed := make(chan struct{})
+		go func() {
+			hook.Flush()
+			close(flushed)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		logger.Warn("event dropped")
+		<-flushed
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		logger.WithField(fieldFinalizers, fins).Error(message)
+		logger.WithField(fieldFinalizers, fins).Error(message)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a finalizer logging while the hook is flushed should not deadlock")
+	}
+}
@@ -0,0 +1,83 @@
+package logrus_sentry
+
+import (
+	"regexp"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	templateUUIDRe   = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	templateHexRe    = regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b|\b[0-9a-fA-F]{16,}\b`)
+	templateNumberRe = regexp.MustCompile(`\d+`)
+)
+
+// fingerprintRule sets the fingerprint of the entries it matches.
+type fingerprintRule struct {
+	match       func(entry *logrus.Entry) bool
+	fingerprint func(entry *logrus.Entry) []string
+}
+
+// AddFingerprintRule adds a rule setting the fingerprint of the entries
+// matched by matcher, or of all entries if matcher is nil. Rules are
+// evaluated in the order they were added, and the first one returning a
+// non-empty fingerprint is used. The fingerprint field takes precedence
+// over the rules, and the rules over the level fingerprints.
+//
+//	hook.AddFingerprintRule(nil, logrus_sentry.MessageTemplateFingerprint)
+func (hook *SentryHook) AddFingerprintRule(matcher func(entry *logrus.Entry) bool, fingerprint func(entry *logrus.Entry) []string) {
+	hook.fingerprintRules = append(hook.fingerprintRules, fingerprintRule{
+		match:       matcher,
+		fingerprint: fingerprint,
+	})
+}
+
+// ruleFingerprint returns the fingerprint of the first rule matching entry.
+func (hook *SentryHook) ruleFingerprint(entry *logrus.Entry) ([]string, bool) {
+	for _, rule := range hook.fingerprintRules {
+		if rule.match != nil && !rule.match(entry) {
+			continue
+		}
+		if fingerprint := rule.fingerprint(entry); len(fingerprint) != 0 {
+			return fingerprint, true
+		}
+	}
+	return nil, false
+}
+
+// MessageTemplateFingerprint groups the entries by the template of their
+// message, i.e. the message with its UUIDs, hexadecimal values and numbers
+// replaced by placeholders.
+func MessageTemplateFingerprint(entry *logrus.Entry) []string {
+	return []string{messageTemplate(entry.Message)}
+}
+
+// CulpritFingerprint groups the entries by the template of their error, or
+// of their message if they have none, and the function of their top in-app
+// frame: the last in-app frame of the error's stacktrace, or the function
+// the entry was logged from.
+func (hook *SentryHook) CulpritFingerprint(entry *logrus.Entry) []string {
+	culprit := entry.Message
+	function := ""
+	if err, ok := newDataField(entry.Data).getError(); ok {
+		culprit = err.Error()
+		if stacktrace := hook.findStacktrace(err); stacktrace != nil {
+			for _, frame := range stacktrace.Frames {
+				if frame.InApp {
+					function = frame.Module + "." + frame.Function
+				}
+			}
+		}
+	}
+	if function == "" {
+		function = callerFrame(entry).Function
+	}
+	return []string{messageTemplate(culprit), function}
+}
+
+// messageTemplate replaces the variable parts of s by placeholders.
+func messageTemplate(s string) string {
+	s = templateUUIDRe.ReplaceAllString(s, "<uuid>")
+	s = templateHexRe.ReplaceAllString(s, "<hex>")
+	return templateNumberRe.ReplaceAllString(s, "<num>")
+}
@@ -0,0 +1,91 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddFingerprintRule(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetLevelFingerprint(logrus.ErrorLevel, []string{"errors"})
+		hook.AddFingerprintRule(func(entry *logrus.Entry) bool {
+			return strings.HasPrefix(entry.Message, "payment")
+		}, func(entry *logrus.Entry) []string {
+			return []string{"payments"}
+		})
+		hook.AddFingerprintRule(nil, func(entry *logrus.Entry) []string {
+			return nil // no opinion
+		})
+		hook.AddFingerprintRule(func(entry *logrus.Entry) bool {
+			return strings.HasPrefix(entry.Message, "user")
+		}, MessageTemplateFingerprint)
+		logger.Hooks.Add(hook)
+
+		logger.Error("payment 42 failed")
+		packet := <-pch
+		a.Equal([]string{"payments"}, packet.Fingerprint, "first matching rule must be used")
+
+		logger.Error("user 42 not found")
+		packet = <-pch
+		a.Equal([]string{"user <num> not found"}, packet.Fingerprint, "rules returning no fingerprint must be skipped")
+
+		logger.WithField("fingerprint", []string{"custom"}).Error("payment failed")
+		packet = <-pch
+		a.Equal([]string{"custom"}, packet.Fingerprint, "fingerprint field must take precedence")
+
+		logger.Error(message)
+		packet = <-pch
+		a.Equal([]string{"errors"}, packet.Fingerprint, "level fingerprint must be used when no rule matches")
+	})
+}
+
+func TestMessageTemplate(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		message  string
+		expected string
+	}{
+		{"order 1234 failed after 3.5s", "order <num> failed after <num>.<num>s"},
+		{"user 0f8fad5b-d9cb-469f-a165-70867728950e not found", "user <uuid> not found"},
+		{"bad pointer 0xc000123456", "bad pointer <hex>"},
+		{"commit 5d41402abc4b2a76b9719d911017c592 missing", "commit <hex> missing"},
+		{"static message", "static message"},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, messageTemplate(tt.message), tt.message)
+	}
+}
+
+func TestCulpritFingerprint(t *testing.T) {
+	a := assert.New(t)
+
+	hook, err := NewSentryHook("", []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+
+	entry := &logrus.Entry{
+		Data:    logrus.Fields{logrus.ErrorKey: errors.New("order 1234 failed")},
+		Message: message,
+		Caller:  &runtime.Frame{Function: "main.checkout"},
+	}
+	a.Equal([]string{"order <num> failed", "main.checkout"}, hook.CulpritFingerprint(entry))
+
+	entry.Data = logrus.Fields{}
+	a.Equal([]string{message, "main.checkout"}, hook.CulpritFingerprint(entry), "message must be used without error")
+}
@@ -0,0 +1,82 @@
+package logrus_sentry
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// extraGoroutineID is the extra holding the ID of the goroutine which
+	// fired the event.
+	extraGoroutineID = "goroutine_id"
+	// extraGoroutineCreatedBy is the extra holding the creation point of
+	// the goroutine which fired the event.
+	extraGoroutineCreatedBy = "goroutine_created_by"
+
+	// maxGoroutineStack bounds the buffer read by runtime.Stack.
+	maxGoroutineStack = 1 << 20
+)
+
+// SetGoroutineInfo adds to the events of the error level and above the ID of
+// the goroutine which fired them, in the goroutine_id extra, and the function
+// and location which started it, in the goroutine_created_by extra, e.g.
+// "main.startWorkers in goroutine 1 at /app/main.go:42", which tells which
+// worker of a pool hit the error. They are read from runtime.Stack, which
+// costs a few microseconds per event.
+func (hook *SentryHook) SetGoroutineInfo(enabled bool) {
+	hook.goroutineInfo = enabled
+}
+
+// currentGoroutine returns the ID and the creation point of the current
+// goroutine. The creation point is empty for the main goroutine.
+func currentGoroutine() (id int, createdBy string, ok bool) {
+	buf := make([]byte, 4<<10)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) || len(buf) >= maxGoroutineStack {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	return parseGoroutineStack(buf)
+}
+
+// parseGoroutineStack parses the output of runtime.Stack for a goroutine:
+//
+//	goroutine 18 [running]:
+//	...
+//	created by main.startWorkers in goroutine 1
+//		/app/main.go:42 +0x1d
+func parseGoroutineStack(stack []byte) (id int, createdBy string, ok bool) {
+	lines := strings.Split(string(bytes.TrimSpace(stack)), "\n")
+	header := strings.TrimPrefix(lines[0], "goroutine ")
+	if header == lines[0] {
+		return 0, "", false
+	}
+	if i := strings.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, err := strconv.Atoi(header)
+	if err != nil {
+		return 0, "", false
+	}
+
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "created by ") {
+			continue
+		}
+		createdBy = strings.TrimPrefix(line, "created by ")
+		if i+1 < len(lines) {
+			location := strings.TrimSpace(lines[i+1])
+			if j := strings.LastIndex(location, " +0x"); j >= 0 {
+				location = location[:j]
+			}
+			createdBy += " at " + location
+		}
+		break
+	}
+	return id, createdBy, true
+}
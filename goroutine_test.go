@@ -0,0 +1,74 @@
+package logrus_sentry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGoroutineStack(t *testing.T) {
+	a := assert.New(t)
+
+	id, createdBy, ok := parseGoroutineStack([]byte(`goroutine 18 [running]:
+main.work(0x1)
+	/app/worker.go:12 +0x25
+created by main.startWorkers in goroutine 1
+	/app/main.go:42 +0x1d
+`))
+	a.True(ok)
+	a.Equal(18, id)
+	a.Equal("main.startWorkers in goroutine 1 at /app/main.go:42", createdBy)
+
+	// before go1.21
+	_, createdBy, _ = parseGoroutineStack([]byte(`goroutine 7 [running]:
+created by main.startWorkers
+	/app/main.go:42 +0x1d
+`))
+	a.Equal("main.startWorkers at /app/main.go:42", createdBy)
+
+	id, createdBy, ok = parseGoroutineStack([]byte("goroutine 1 [running]:\nmain.main()\n\t/app/main.go:5 +0x1\n"))
+	a.True(ok)
+	a.Equal(1, id)
+	a.Equal("", createdBy, "the main goroutine has no creation point")
+
+	_, _, ok = parseGoroutineStack([]byte("garbage"))
+	a.False(ok)
+}
+
+// logFromWorker logs entry from a new goroutine.
+func logFromWorker(entry *logrus.Entry, msg string) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		entry.Error(msg)
+	}()
+	<-done
+}
+
+func TestSetGoroutineInfo(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.SetGoroutineInfo(true)
+		logger.Hooks.Add(hook)
+
+		logFromWorker(logrus.NewEntry(logger), message)
+		packet := <-pch
+		a.NotZero(packet.Extra[extraGoroutineID])
+		createdBy, _ := packet.Extra[extraGoroutineCreatedBy].(string)
+		a.True(strings.HasPrefix(createdBy, "github.com/musqdp/logrus_sentry.logFromWorker"), createdBy)
+		a.Contains(createdBy, "goroutine_test.go:")
+
+		logger.Warn(message)
+		packet = <-pch
+		a.NotContains(packet.Extra, extraGoroutineID, "only errors should have the goroutine info")
+	})
+}
@@ -0,0 +1,140 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	// healthMessage is the message of the health reports, followed by the
+	// number of dropped events.
+	healthMessage = "telemetry health: %d events dropped"
+	// extraHealthSince is the extra holding the start of the period of a
+	// health report.
+	extraHealthSince = "since"
+)
+
+// healthFingerprint groups the health reports into a single issue.
+var healthFingerprint = []string{"logrus_sentry", "telemetry_health"}
+
+// healthReport sends the drops counted since the last report sent.
+type healthReport struct {
+	stop  chan struct{}
+	last  Stats
+	since time.Time
+}
+
+// EnableHealthReport sends every interval an info event summarizing the
+// events the hook dropped since the previous report, by reason, e.g.
+// dropped_by_sampling or dropped_by_throttle, so that the gaps in the data
+// can be explained from within sentry. No report is sent if no event was
+// dropped. The reports are not sampled, since a report sampled out would be
+// a drop counted in the next one. A report due while the sends are paused is
+// merged into the next one. A zero interval stops the reports.
+func (hook *SentryHook) EnableHealthReport(interval time.Duration) {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	hook.stopHealthReport()
+	if interval <= 0 {
+		return
+	}
+
+	r := &healthReport{
+		stop:  make(chan struct{}),
+		last:  hook.Stats(),
+		since: time.Now(),
+	}
+	hook.health = r
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				hook.reportHealth(r, now)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopHealthReport stops the reports. hook.mu must be held.
+func (hook *SentryHook) stopHealthReport() {
+	if r := hook.health; r != nil {
+		close(r.stop)
+		hook.health = nil
+	}
+}
+
+// reportHealth sends the drops counted since the last report sent.
+func (hook *SentryHook) reportHealth(r *healthReport, now time.Time) {
+	hook.pause.mu.Lock()
+	paused := hook.pause.paused
+	hook.pause.mu.Unlock()
+	if paused {
+		return
+	}
+
+	stats := hook.Stats()
+	dropped := droppedSince(stats, r.last)
+	var total uint64
+	for _, n := range dropped {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+
+	packet := raven.NewPacket(fmt.Sprintf(healthMessage, total))
+	packet.Level = raven.INFO
+	packet.Fingerprint = healthFingerprint
+	packet.Extra = make(map[string]interface{}, len(dropped)+1)
+	for reason, n := range dropped {
+		packet.Extra[reason] = n
+	}
+	packet.Extra[extraHealthSince] = r.since.Format(time.RFC3339)
+	if eventID := hook.captureUnsampled(packet); eventID == "" {
+		// excluded by the client: report the drops next time
+		return
+	}
+	r.last = stats
+	r.since = now
+}
+
+// captureUnsampled captures packet with the default client, bypassing the
+// sample rate set by SetSampleRate. It holds hook.mu exclusively, so that no
+// entry is captured meanwhile.
+func (hook *SentryHook) captureUnsampled(packet *raven.Packet) string {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+
+	if hook.sampleRateSet && hook.sampleRate < 1 {
+		_ = hook.client.SetSampleRate(1)
+		defer hook.client.SetSampleRate(hook.sampleRate)
+	}
+	eventID, _ := hook.client.Capture(packet, nil)
+	return eventID
+}
+
+// droppedSince returns the number of events dropped between last and stats,
+// by reason.
+func droppedSince(stats, last Stats) map[string]uint64 {
+	dropped := map[string]uint64{
+		"dropped_by_sampling":     stats.DroppedBySampling - last.DroppedBySampling,
+		"dropped_by_ignore_rules": stats.DroppedByIgnoreRules - last.DroppedByIgnoreRules,
+		"dropped_by_throttle":     stats.DroppedByThrottle - last.DroppedByThrottle,
+		"dropped_by_threshold":    stats.DroppedByThreshold - last.DroppedByThreshold,
+		"dropped_by_queue":        stats.DroppedByQueue - last.DroppedByQueue,
+		"dropped_by_overflow":     stats.DroppedByOverflow - last.DroppedByOverflow,
+		"dropped_by_pause":        stats.DroppedByPause - last.DroppedByPause,
+	}
+	for reason, n := range dropped {
+		if n == 0 {
+			delete(dropped, reason)
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,66 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthReport(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.Timeout = 0
+		a.NoError(hook.SetIgnoreErrors("^ignored"))
+		hook.SetThrottle(1, time.Hour)
+		hook.EnableHealthReport(time.Hour)
+		defer hook.EnableHealthReport(0)
+		logger.Hooks.Add(hook)
+
+		r := hook.health
+		hook.reportHealth(r, time.Now())
+		select {
+		case <-pch:
+			a.Fail("no report should be sent without drops")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		logger.Error("ignored message")
+		logger.WithField("fingerprint", []string{"throttled"}).Error(message)
+		<-pch
+		logger.WithField("fingerprint", []string{"throttled"}).Error(message)
+		a.NoError(hook.SetSampleRate(0))
+		logger.Error("sampled message")
+		stats := hook.Stats()
+		a.Equal(uint64(1), stats.DroppedByIgnoreRules)
+		a.Equal(uint64(1), stats.DroppedBySampling)
+
+		// the report is not sampled out
+		hook.reportHealth(r, time.Now())
+		packet := <-pch
+		a.Equal(fmt.Sprintf(healthMessage, 3), packet.Message)
+		a.Equal("info", string(packet.Level))
+		a.Equal(healthFingerprint, packet.Fingerprint)
+		a.Equal(float64(1), packet.Extra["dropped_by_ignore_rules"])
+		a.Equal(float64(1), packet.Extra["dropped_by_throttle"])
+		a.Equal(float64(1), packet.Extra["dropped_by_sampling"])
+		a.NotContains(packet.Extra, "dropped_by_queue")
+		a.Contains(packet.Extra, extraHealthSince)
+
+		logger.Error("sampled again")
+		a.Equal(uint64(2), hook.Stats().DroppedBySampling, "the sample rate should be restored")
+		a.NoError(hook.SetSampleRate(1))
+		logger.Error("ignored again")
+		hook.reportHealth(r, time.Now())
+		packet = <-pch
+		a.Equal(fmt.Sprintf(healthMessage, 2), packet.Message, "the report should count the drops since the previous one")
+	})
+}
@@ -0,0 +1,49 @@
+package logrus_sentry
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	// extraGoroutineLabels is the extra holding the profiler labels too long
+	// to be sent as tags.
+	extraGoroutineLabels = "goroutine_labels"
+
+	// maxTagValueLength is the maximum length of a tag value accepted by
+	// sentry.
+	maxTagValueLength = 200
+)
+
+// applyLabels adds the profiler labels of ctx, set by pprof.Do or
+// pprof.WithLabels, as tags of the packet. Tags already set from the
+// entry's fields or the context win.
+func applyLabels(ctx context.Context, packet *raven.Packet) {
+	set := make(map[string]struct{}, len(packet.Tags))
+	for _, tag := range packet.Tags {
+		set[tag.Key] = struct{}{}
+	}
+
+	tags := make(map[string]string)
+	long := make(map[string]interface{})
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		if _, ok := set[key]; ok {
+			return true
+		}
+		if len(value) > maxTagValueLength {
+			long[key] = value
+		} else {
+			tags[key] = value
+		}
+		return true
+	})
+	packet.AddTags(tags)
+	if len(long) != 0 {
+		if packet.Extra == nil {
+			packet.Extra = make(map[string]interface{})
+		}
+		setExtra(packet.Extra, extraGoroutineLabels, long)
+	}
+}
@@ -0,0 +1,119 @@
+package logrus_sentry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	// extraEncodingSuffix is the suffix of the extra telling how the extra
+	// of the same name was encoded.
+	extraEncodingSuffix = "_encoding"
+
+	encodingGzipBase64 = "gzip+base64"
+	encodingAttachment = "attachment"
+)
+
+// LargeExtraMode is how the large string extras are kept whole.
+type LargeExtraMode int
+
+const (
+	// LargeExtraGzip sends the large string extras gzipped and base64
+	// encoded, which DecodeExtra reverts.
+	LargeExtraGzip LargeExtraMode = iota
+	// LargeExtraAttachment sends the large string extras as attachments of
+	// the event.
+	LargeExtraAttachment
+)
+
+type largeExtras struct {
+	threshold int
+	mode      LargeExtraMode
+}
+
+// SetLargeExtras keeps whole the string extras larger than threshold bytes,
+// e.g. SQL statements or goroutine dumps, instead of having them truncated by
+// the size limits. With LargeExtraGzip, they are sent gzipped and base64
+// encoded when it makes them smaller and fits the size limit, and the extra
+// <key>_encoding is set to "gzip+base64". With LargeExtraAttachment, they are
+// sent as the attachment <key>.txt, the extra <key> names the attachment and
+// <key>_encoding is set to "attachment". The extras are not encoded if the
+// entry has a <key>_encoding field. A threshold of zero disables it.
+func (hook *SentryHook) SetLargeExtras(threshold int, mode LargeExtraMode) {
+	if threshold <= 0 {
+		hook.largeExtras = nil
+		return
+	}
+	hook.largeExtras = &largeExtras{threshold: threshold, mode: mode}
+}
+
+// DecodeExtra returns the original value of an extra sent gzipped and base64
+// encoded by the hook.
+func DecodeExtra(value string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err = ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// encodeLargeExtra encodes the value of the extra key if it is a large
+// string, or moves it to an attachment of df. It returns the value to send
+// and its encoding, empty if it was not encoded. The values whose encoding
+// extra would collide with a field of the entry are not encoded.
+func (hook *SentryHook) encodeLargeExtra(df *dataField, key string, value interface{}) (interface{}, string) {
+	l := hook.largeExtras
+	s, ok := value.(string)
+	if l == nil || !ok || len(s) <= l.threshold {
+		return value, ""
+	}
+	if _, ok := df.data[key+extraEncodingSuffix]; ok {
+		return value, ""
+	}
+
+	switch l.mode {
+	case LargeExtraAttachment:
+		filename := key + ".txt"
+		df.attachments = append(df.attachments, Attachment{
+			Filename:    filename,
+			ContentType: "text/plain",
+			Payload:     []byte(s),
+		})
+		return fmt.Sprintf("[attachment %s]", filename), encodingAttachment
+	default:
+		encoded, err := gzipBase64(s)
+		if err != nil || len(encoded) >= len(s) {
+			return value, ""
+		}
+		if limit := hook.extraSizeLimitOf(key); limit > 0 && jsonSize(encoded) > limit {
+			// truncated, it could not be decoded
+			return value, ""
+		}
+		return encoded, encodingGzipBase64
+	}
+}
+
+// gzipBase64 returns s gzipped and base64 encoded.
+func gzipBase64(s string) (string, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
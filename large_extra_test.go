@@ -0,0 +1,89 @@
+package logrus_sentry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLargeExtrasGzip(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetExtraSizeLimit(4096)
+		hook.SetLargeExtras(1024, LargeExtraGzip)
+		logger.Hooks.Add(hook)
+
+		sql := strings.Repeat("SELECT id, name FROM users WHERE id = 1;\n", 1000)
+		logger.WithFields(logrus.Fields{
+			"sql":   sql,
+			"query": "SELECT 1",
+		}).Error(message)
+		packet := <-pch
+		a.Equal(encodingGzipBase64, packet.Extra["sql_encoding"])
+		decoded, err := DecodeExtra(packet.Extra["sql"].(string))
+		a.NoError(err)
+		a.Equal(sql, decoded, "the large extra should be recoverable")
+		a.Equal("SELECT 1", packet.Extra["query"], "small extras should be sent as is")
+		a.NotContains(packet.Extra, "query_encoding")
+
+		logger.WithFields(logrus.Fields{
+			"sql":          sql,
+			"sql_encoding": "utf-8",
+		}).Error(message)
+		packet = <-pch
+		a.Equal("utf-8", packet.Extra["sql_encoding"], "fields should not be overwritten")
+		a.True(strings.HasPrefix(packet.Extra["sql"].(string), "SELECT"), "extras colliding with a field should not be encoded")
+	})
+}
+
+func TestSetLargeExtrasFallback(t *testing.T) {
+	a := assert.New(t)
+
+	hook, err := NewSentryHook("", []logrus.Level{logrus.ErrorLevel})
+	a.NoError(err)
+	hook.SetExtraSizeLimit(100)
+	hook.SetLargeExtras(10, LargeExtraGzip)
+
+	df := newDataField(logrus.Fields{
+		// random-looking data does not compress
+		"token": "q8Zr1Kx0pV7mWc3Ld9Ty",
+		// compressed, it exceeds the size limit
+		"dump": strings.Repeat("goroutine 1 [running]:\n", 1000),
+	})
+	extra := hook.formatExtraData(df, hook.escaping)
+	a.Equal("q8Zr1Kx0pV7mWc3Ld9Ty", extra["token"])
+	a.NotContains(extra, "token_encoding")
+	a.True(strings.HasSuffix(extra["dump"].(string), truncatedSuffix), "extras not fitting the size limit once compressed should be truncated")
+	a.NotContains(extra, "dump_encoding")
+}
+
+func TestSetLargeExtrasAttachment(t *testing.T) {
+	a := assert.New(t)
+
+	hook, err := NewSentryHook("", []logrus.Level{logrus.ErrorLevel})
+	a.NoError(err)
+	hook.SetExtraSizeLimit(100)
+	hook.SetLargeExtras(100, LargeExtraAttachment)
+
+	dump := strings.Repeat("goroutine 1 [running]:\n", 100)
+	df := newDataField(logrus.Fields{"dump": dump})
+	extra := hook.formatExtraData(df, hook.escaping)
+	a.Equal("[attachment dump.txt]", extra["dump"])
+	a.Equal(encodingAttachment, extra["dump_encoding"])
+	a.Equal([]Attachment{{Filename: "dump.txt", ContentType: "text/plain", Payload: []byte(dump)}}, df.attachments)
+
+	hook.SetLargeExtras(0, LargeExtraAttachment)
+	df = newDataField(logrus.Fields{"dump": dump})
+	extra = hook.formatExtraData(df, hook.escaping)
+	a.True(strings.HasSuffix(extra["dump"].(string), truncatedSuffix))
+	a.Empty(df.attachments)
+}
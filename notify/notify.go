@@ -0,0 +1,261 @@
+// Package notify renders compact chat notifications (Slack, Microsoft Teams)
+// from outgoing raven packets, so that callbacks which receive a packet after
+// it was sent do not have to reimplement the formatting themselves.
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/musqdp/raven-go"
+)
+
+// Annotation is the color and emoji used to decorate a notification.
+type Annotation struct {
+	// Color is a hex color code including the leading '#'.
+	Color string
+	// Emoji is a Slack style emoji shortcode, e.g. ":warning:".
+	Emoji string
+}
+
+var (
+	severityAnnotations = map[raven.Severity]Annotation{
+		raven.DEBUG:   {Color: "#9E9E9E", Emoji: ":mag:"},
+		raven.INFO:    {Color: "#2F80ED", Emoji: ":information_source:"},
+		raven.WARNING: {Color: "#F2C744", Emoji: ":warning:"},
+		raven.ERROR:   {Color: "#E03E2F", Emoji: ":red_circle:"},
+		raven.FATAL:   {Color: "#8B0000", Emoji: ":rotating_light:"},
+	}
+
+	defaultAnnotation = severityAnnotations[raven.ERROR]
+)
+
+// Annotate returns the color and emoji for the given severity.
+// Unknown severities are annotated as errors.
+func Annotate(level raven.Severity) Annotation {
+	if a, ok := severityAnnotations[level]; ok {
+		return a
+	}
+	return defaultAnnotation
+}
+
+// Message is a chat agnostic summary of a packet.
+type Message struct {
+	Title    string
+	Culprit  string
+	TopFrame string
+	EventURL string
+	Level    raven.Severity
+
+	Annotation
+}
+
+// NewMessage builds a Message from the packet.
+// eventURL is the link to the event in the Sentry UI and may be empty.
+func NewMessage(packet *raven.Packet, eventURL string) Message {
+	level := packet.Level
+	if level == "" {
+		level = raven.ERROR
+	}
+	return Message{
+		Title:      packet.Message,
+		Culprit:    packet.Culprit,
+		TopFrame:   formatFrame(topFrame(packet)),
+		EventURL:   eventURL,
+		Level:      level,
+		Annotation: Annotate(level),
+	}
+}
+
+// EventURL returns the Sentry UI link of an event.
+// baseURL is the organization's issue search page,
+// e.g. "https://sentry.io/organizations/myorg/issues/".
+func EventURL(baseURL, eventID string) string {
+	if baseURL == "" || eventID == "" {
+		return ""
+	}
+	return strings.TrimRight(baseURL, "/") + "/?query=" + eventID
+}
+
+var (
+	markdownEscaper = strings.NewReplacer(
+		`\`, `\\`,
+		"`", "\\`",
+		`*`, `\*`,
+		`_`, `\_`,
+		`~`, `\~`,
+		`[`, `\[`,
+		`]`, `\]`,
+		`|`, `\|`,
+		`<`, `&lt;`,
+		`>`, `&gt;`,
+	)
+	slackEscaper = strings.NewReplacer(
+		`&`, `&amp;`,
+		`<`, `&lt;`,
+		`>`, `&gt;`,
+	)
+)
+
+// EscapeMarkdown escapes the markdown syntax of s, for Text and Teams.
+func EscapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// EscapeSlack escapes the control characters of Slack messages in s, for
+// Slack.
+func EscapeSlack(s string) string {
+	return slackEscaper.Replace(s)
+}
+
+// Escaped returns the message with its title escaped by escape, e.g.
+// EscapeSlack, so that a user controlled message does not mangle the
+// notification. The culprit and top frame are rendered as code.
+func (m Message) Escaped(escape func(string) string) Message {
+	m.Title = escape(m.Title)
+	return m
+}
+
+// Text renders the message as plain markdown lines.
+func (m Message) Text() string {
+	lines := []string{fmt.Sprintf("%s *[%s] %s*", m.Emoji, strings.ToUpper(string(m.Level)), m.Title)}
+	if m.Culprit != "" {
+		lines = append(lines, "Culprit: `"+m.Culprit+"`")
+	}
+	if m.TopFrame != "" {
+		lines = append(lines, "At: `"+m.TopFrame+"`")
+	}
+	if m.EventURL != "" {
+		lines = append(lines, m.EventURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Slack renders the message as a Slack incoming webhook payload.
+func (m Message) Slack() ([]byte, error) {
+	type field struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+		Short bool   `json:"short"`
+	}
+	type attachment struct {
+		Fallback  string  `json:"fallback"`
+		Color     string  `json:"color"`
+		Title     string  `json:"title"`
+		TitleLink string  `json:"title_link,omitempty"`
+		Fields    []field `json:"fields,omitempty"`
+	}
+
+	att := attachment{
+		Fallback:  m.Text(),
+		Color:     m.Color,
+		Title:     m.Emoji + " " + m.Title,
+		TitleLink: m.EventURL,
+	}
+	if m.Culprit != "" {
+		att.Fields = append(att.Fields, field{Title: "Culprit", Value: m.Culprit})
+	}
+	if m.TopFrame != "" {
+		att.Fields = append(att.Fields, field{Title: "Top frame", Value: m.TopFrame})
+	}
+	return json.Marshal(map[string]interface{}{
+		"attachments": []attachment{att},
+	})
+}
+
+// Teams renders the message as a Microsoft Teams connector MessageCard.
+func (m Message) Teams() ([]byte, error) {
+	type fact struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	type section struct {
+		ActivityTitle string `json:"activityTitle"`
+		Facts         []fact `json:"facts,omitempty"`
+	}
+	type target struct {
+		OS  string `json:"os"`
+		URI string `json:"uri"`
+	}
+	type action struct {
+		Type    string   `json:"@type"`
+		Name    string   `json:"name"`
+		Targets []target `json:"targets"`
+	}
+	type card struct {
+		Type       string    `json:"@type"`
+		Context    string    `json:"@context"`
+		Summary    string    `json:"summary"`
+		ThemeColor string    `json:"themeColor"`
+		Sections   []section `json:"sections"`
+		Actions    []action  `json:"potentialAction,omitempty"`
+	}
+
+	sec := section{ActivityTitle: m.Emoji + " " + m.Title}
+	if m.Culprit != "" {
+		sec.Facts = append(sec.Facts, fact{Name: "Culprit", Value: m.Culprit})
+	}
+	if m.TopFrame != "" {
+		sec.Facts = append(sec.Facts, fact{Name: "Top frame", Value: m.TopFrame})
+	}
+	c := card{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		Summary:    m.Title,
+		ThemeColor: strings.TrimPrefix(m.Color, "#"),
+		Sections:   []section{sec},
+	}
+	if m.EventURL != "" {
+		c.Actions = []action{{
+			Type:    "OpenUri",
+			Name:    "View in Sentry",
+			Targets: []target{{OS: "default", URI: m.EventURL}},
+		}}
+	}
+	return json.Marshal(c)
+}
+
+// topFrame returns the most recent frame of the packet's stacktrace,
+// preferring in_app frames.
+func topFrame(packet *raven.Packet) *raven.StacktraceFrame {
+	var frames []*raven.StacktraceFrame
+	for _, in := range packet.Interfaces {
+		switch v := in.(type) {
+		case *raven.Exception:
+			if v.Stacktrace != nil {
+				frames = v.Stacktrace.Frames
+			}
+		case *raven.Stacktrace:
+			frames = v.Frames
+		}
+		if len(frames) != 0 {
+			break
+		}
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+
+	// Sentry orders frames with the oldest first
+	for i := len(frames) - 1; i >= 0; i-- {
+		if frames[i] != nil && frames[i].InApp {
+			return frames[i]
+		}
+	}
+	return frames[len(frames)-1]
+}
+
+func formatFrame(frame *raven.StacktraceFrame) string {
+	if frame == nil {
+		return ""
+	}
+	s := frame.Filename
+	if frame.Lineno != 0 {
+		s = fmt.Sprintf("%s:%d", s, frame.Lineno)
+	}
+	if frame.Function != "" {
+		s = fmt.Sprintf("%s in %s", s, frame.Function)
+	}
+	return s
+}
@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnotate(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		level raven.Severity
+		color string
+		emoji string
+	}{
+		{raven.DEBUG, "#9E9E9E", ":mag:"},
+		{raven.INFO, "#2F80ED", ":information_source:"},
+		{raven.WARNING, "#F2C744", ":warning:"},
+		{raven.ERROR, "#E03E2F", ":red_circle:"},
+		{raven.FATAL, "#8B0000", ":rotating_light:"},
+		{raven.Severity("unknown"), "#E03E2F", ":red_circle:"},
+	}
+
+	for _, tt := range tests {
+		target := fmt.Sprintf("%+v", tt)
+
+		an := Annotate(tt.level)
+		a.Equal(tt.color, an.Color, target)
+		a.Equal(tt.emoji, an.Emoji, target)
+	}
+}
+
+func TestNewMessage(t *testing.T) {
+	a := assert.New(t)
+
+	st := &raven.Stacktrace{Frames: []*raven.StacktraceFrame{
+		{Filename: "main.go", Function: "main", Lineno: 10, InApp: true},
+		{Filename: "app/handler.go", Function: "handle", Lineno: 42, InApp: true},
+		{Filename: "net/http/server.go", Function: "serve", Lineno: 7},
+	}}
+	packet := raven.NewPacket("boom", raven.NewException(fmt.Errorf("boom"), st))
+	packet.Level = raven.WARNING
+	packet.Culprit = "app.handle"
+
+	msg := NewMessage(packet, "https://sentry.example.com/event")
+	a.Equal("boom", msg.Title)
+	a.Equal("app.handle", msg.Culprit)
+	a.Equal("app/handler.go:42 in handle", msg.TopFrame, "top frame should be the most recent in_app frame")
+	a.Equal("https://sentry.example.com/event", msg.EventURL)
+	a.Equal(Annotate(raven.WARNING), msg.Annotation)
+
+	msg = NewMessage(raven.NewPacket("no stack"), "")
+	a.Equal("", msg.TopFrame)
+	a.Equal(raven.ERROR, msg.Level, "empty level should default to error")
+}
+
+func TestEventURL(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("https://sentry.io/organizations/o/issues/?query=abc", EventURL("https://sentry.io/organizations/o/issues/", "abc"))
+	a.Equal("", EventURL("", "abc"))
+	a.Equal("", EventURL("https://sentry.io/", ""))
+}
+
+func TestSlack(t *testing.T) {
+	a := assert.New(t)
+
+	msg := Message{
+		Title:      "boom",
+		Culprit:    "app.handle",
+		TopFrame:   "handler.go:42",
+		EventURL:   "https://sentry.example.com/event",
+		Level:      raven.ERROR,
+		Annotation: Annotate(raven.ERROR),
+	}
+	b, err := msg.Slack()
+	a.NoError(err)
+
+	var payload struct {
+		Attachments []struct {
+			Color     string `json:"color"`
+			Title     string `json:"title"`
+			TitleLink string `json:"title_link"`
+			Fields    []struct {
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"attachments"`
+	}
+	a.NoError(json.Unmarshal(b, &payload))
+	a.Len(payload.Attachments, 1)
+	att := payload.Attachments[0]
+	a.Equal("#E03E2F", att.Color)
+	a.Equal(":red_circle: boom", att.Title)
+	a.Equal(msg.EventURL, att.TitleLink)
+	a.Len(att.Fields, 2)
+}
+
+func TestTeams(t *testing.T) {
+	a := assert.New(t)
+
+	msg := Message{
+		Title:      "boom",
+		EventURL:   "https://sentry.example.com/event",
+		Level:      raven.FATAL,
+		Annotation: Annotate(raven.FATAL),
+	}
+	b, err := msg.Teams()
+	a.NoError(err)
+
+	var card struct {
+		ThemeColor string `json:"themeColor"`
+		Actions    []struct {
+			Targets []struct {
+				URI string `json:"uri"`
+			} `json:"targets"`
+		} `json:"potentialAction"`
+	}
+	a.NoError(json.Unmarshal(b, &card))
+	a.Equal("8B0000", card.ThemeColor)
+	a.Len(card.Actions, 1)
+	a.Equal(msg.EventURL, card.Actions[0].Targets[0].URI)
+}
+
+func TestEscaped(t *testing.T) {
+	a := assert.New(t)
+
+	msg := NewMessage(raven.NewPacket("user <@channel> sent *bold* [link](x) & more"), "")
+	a.Equal("user &lt;@channel&gt; sent *bold* [link](x) &amp; more", msg.Escaped(EscapeSlack).Title)
+	a.Equal(`user &lt;@channel&gt; sent \*bold\* \[link\](x) & more`, msg.Escaped(EscapeMarkdown).Title)
+	a.Equal("user <@channel> sent *bold* [link](x) & more", msg.Title, "the message should not be modified")
+}
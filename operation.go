@@ -0,0 +1,72 @@
+package logrus_sentry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	extraOperation         = "operation"
+	extraOperationDuration = "operation_duration"
+)
+
+type operationKey struct{}
+
+// operation is an operation timed by TimeOperation.
+type operation struct {
+	name  string
+	start time.Time
+	// outer is the operation running when this one started
+	outer *operation
+	ended uint32
+}
+
+// TimeOperation starts timing the operation name, and returns an entry
+// derived from entry carrying it in its context. Events logged with the
+// returned entry, or an entry derived from it, until the returned function
+// is called carry the operation name and the time elapsed since its start as
+// the operation and operation_duration extras:
+//
+//	entry, end := hook.TimeOperation(entry, "db.migrate")
+//	defer end()
+//
+// Operations can be nested: once the inner one ended, the events carry the
+// outer one. entry is not modified.
+func (hook *SentryHook) TimeOperation(entry *logrus.Entry, name string) (*logrus.Entry, func()) {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	op := &operation{
+		name:  name,
+		start: time.Now(),
+		outer: operationFromContext(ctx),
+	}
+	return entry.WithContext(context.WithValue(ctx, operationKey{}, op)), func() {
+		atomic.StoreUint32(&op.ended, 1)
+	}
+}
+
+// operationFromContext returns the innermost running operation carried by
+// ctx, or nil.
+func operationFromContext(ctx context.Context) *operation {
+	if ctx == nil {
+		return nil
+	}
+	op, _ := ctx.Value(operationKey{}).(*operation)
+	for op != nil && atomic.LoadUint32(&op.ended) != 0 {
+		op = op.outer
+	}
+	return op
+}
+
+// elapsed returns the duration of the operation at the time of entry.
+func (op *operation) elapsed(entry *logrus.Entry) time.Duration {
+	if entry.Time.IsZero() {
+		return time.Since(op.start)
+	}
+	return entry.Time.Sub(op.start)
+}
@@ -0,0 +1,59 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeOperation(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		logger := getTestLogger()
+		logger.Hooks.Add(hook)
+		entry := logrus.NewEntry(logger)
+
+		func() {
+			entry, end := hook.TimeOperation(entry, "db.migrate")
+			defer end()
+			func() {
+				entry, end := hook.TimeOperation(entry, "db.migrate.users")
+				defer end()
+				a.NotNil(entry.Context)
+			}()
+			time.Sleep(10 * time.Millisecond)
+			entry.WithError(errors.New("migration failed")).Error(message)
+		}()
+
+		packet := <-pch
+		a.Equal("db.migrate", packet.Extra["operation"], "outer operation should be restored")
+		duration, err := time.ParseDuration(packet.Extra["operation_duration"].(string))
+		a.NoError(err)
+		a.True(duration >= 10*time.Millisecond, "duration should be the time elapsed since the start")
+
+		timed, end := hook.TimeOperation(entry, "db.vacuum")
+		a.Empty(entry.Data, "the entry should not be modified")
+		a.Nil(entry.Context, "the entry should not be modified")
+		end()
+		timed.Error(message)
+		packet = <-pch
+		a.NotContains(packet.Extra, "operation", "operation should end with the returned function")
+		a.NotContains(packet.Extra, "operation_duration")
+
+		timed, end = hook.TimeOperation(entry, "db.backup")
+		defer end()
+		timed.WithField("operation", "nightly").Error(message)
+		packet = <-pch
+		a.Equal("nightly", packet.Extra["operation"], "the field should not be overwritten")
+		a.Contains(packet.Extra, "operation_duration")
+	})
+}
@@ -0,0 +1,132 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// tagHook is the tag holding the type of a hook which panicked.
+const tagHook = "hook"
+
+// panicError is the error reported for a recovered panic. It carries the
+// stacktrace of the panicking goroutine.
+type panicError struct {
+	value      interface{}
+	stacktrace *raven.Stacktrace
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprint(e.value)
+}
+
+// Cause returns the panic value if it is an error.
+func (e *panicError) Cause() error {
+	if err, ok := e.value.(error); ok {
+		return err
+	}
+	return nil
+}
+
+func (e *panicError) GetStacktrace() *raven.Stacktrace {
+	return e.stacktrace
+}
+
+// SetRepanic sets whether RecoverAndReport and CapturePanic panic again with
+// the recovered value once it is reported.
+func (hook *SentryHook) SetRepanic(repanic bool) {
+	hook.repanic = repanic
+}
+
+// RecoverAndReport recovers from a panic and reports it with the stacktrace
+// of the panicking goroutine, waiting for its delivery. It must be deferred
+// directly:
+//
+//	defer hook.RecoverAndReport()
+func (hook *SentryHook) RecoverAndReport() {
+	if r := recover(); r != nil {
+		hook.reportPanic(r, nil, hook.repanic)
+	}
+}
+
+// CapturePanic calls f and reports the panic it raises, if any, the same way
+// as RecoverAndReport. It returns the recovered value.
+func (hook *SentryHook) CapturePanic(f func()) (recovered interface{}) {
+	defer func() {
+		if recovered = recover(); recovered != nil {
+			hook.reportPanic(recovered, nil, hook.repanic)
+		}
+	}()
+	f()
+	return nil
+}
+
+// reportPanic sends the panic with the fields, waits for its delivery, and
+// panics again with value if repanic is set. It must be called from the
+// deferred function which recovered the panic.
+func (hook *SentryHook) reportPanic(value interface{}, fields logrus.Fields, repanic bool) {
+	stConfig := &hook.StacktraceConfiguration
+	// skip reportPanic and the deferred function
+	stacktrace := raven.NewStacktrace(2, stConfig.Context, stConfig.InAppPrefixes)
+
+	entry := &logrus.Entry{
+		Data: logrus.Fields{
+			logrus.ErrorKey: &panicError{value: value, stacktrace: stacktrace},
+		},
+		Time:    time.Now(),
+		Level:   logrus.PanicLevel,
+		Message: fmt.Sprintf("panic: %v", value),
+	}
+	for k, v := range fields {
+		entry.Data[k] = v
+	}
+	hook.Fire(entry)
+	hook.Flush()
+	client, release := hook.acquireClient(entry)
+	client.Wait()
+	release()
+
+	if repanic {
+		panic(value)
+	}
+}
+
+// guardedHook reports the panics of the hook it wraps instead of crashing.
+type guardedHook struct {
+	logrus.Hook
+	sentry *SentryHook
+}
+
+func (h *guardedHook) Fire(entry *logrus.Entry) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			hookType := fmt.Sprintf("%T", h.Hook)
+			// never panic again: the guard is there to not crash
+			h.sentry.reportPanic(r, logrus.Fields{
+				fieldTags: raven.Tags{{Key: tagHook, Value: hookType}},
+			}, false)
+			err = fmt.Errorf("hook %s panicked: %v", hookType, r)
+		}
+	}()
+	return h.Hook.Fire(entry)
+}
+
+// GuardHooks wraps the other hooks of logger so that a panic in any of them
+// is recovered and reported, tagged with the type of the hook, instead of
+// crashing the service, whatever SetRepanic sets. The panicking hook's Fire
+// returns an error, which logrus prints to stderr. Hooks added to logger
+// afterwards are not guarded.
+func (hook *SentryHook) GuardHooks(logger *logrus.Logger) {
+	guarded := make(logrus.LevelHooks, len(logger.Hooks))
+	for level, hooks := range logger.Hooks {
+		for _, h := range hooks {
+			if _, ok := h.(*guardedHook); !ok && h != logrus.Hook(hook) {
+				h = &guardedHook{Hook: h, sentry: hook}
+			}
+			guarded[level] = append(guarded[level], h)
+		}
+	}
+	logger.ReplaceHooks(guarded)
+}
@@ -0,0 +1,107 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:noinline
+func panickingFunction() {
+	panic("something bad happened")
+}
+
+func TestRecoverAndReport(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		func() {
+			defer hook.RecoverAndReport()
+			panickingFunction()
+		}()
+
+		packet := <-pch
+		a.Equal("panic: something bad happened", packet.Message)
+		a.Equal("fatal", string(packet.Level))
+		a.Equal("something bad happened", packet.Culprit)
+		if a.NotNil(packet.Exception.Stacktrace, "stacktrace must be sent") {
+			var found bool
+			for _, frame := range packet.Exception.Stacktrace.Frames {
+				found = found || frame.Function == "panickingFunction"
+			}
+			a.True(found, "stacktrace must contain the panicking function")
+		}
+	})
+}
+
+func TestCapturePanic(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		a.Nil(hook.CapturePanic(func() {}), "no panic should be reported")
+
+		panicErr := errors.New("panic error")
+		recovered := hook.CapturePanic(func() { panic(panicErr) })
+		a.Equal(panicErr, recovered)
+		packet := <-pch
+		a.Equal("panic: panic error", packet.Message)
+		a.True(strings.HasSuffix(packet.Exception.Type, "errorString"), "exception type should be the one of the panic error")
+
+		hook.SetRepanic(true)
+		a.PanicsWithValue("again", func() {
+			hook.CapturePanic(func() { panic("again") })
+		}, "panic must be raised again")
+		<-pch
+	})
+}
+
+type panickingHook struct{}
+
+func (panickingHook) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (panickingHook) Fire(*logrus.Entry) error { panic("hook bug") }
+
+func TestGuardHooks(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.SetRepanic(true)
+
+		logger := getTestLogger()
+		logger.Hooks.Add(panickingHook{})
+		hook.GuardHooks(logger)
+		hook.GuardHooks(logger)
+
+		a.NotPanics(func() { logger.Info(message) }, "hook panic should be recovered, even with repanic")
+
+		packet := <-pch
+		a.Equal("panic: hook bug", packet.Message)
+		a.Contains(packet.Tags, raven.Tag{Key: "hook", Value: "logrus_sentry.panickingHook"})
+
+		for _, hooks := range logger.Hooks {
+			for _, h := range hooks {
+				if g, ok := h.(*guardedHook); a.True(ok, "hook should be guarded") {
+					a.Equal(panickingHook{}, g.Hook, "hook should be guarded once")
+				}
+			}
+		}
+	})
+}
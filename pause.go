@@ -0,0 +1,132 @@
+package logrus_sentry
+
+import (
+	"sync"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maxPausedEvents is the maximum number of events buffered while the
+	// sends are paused.
+	maxPausedEvents = 1000
+	// resumeWindow is the number of buffered events sent at once on resume.
+	resumeWindow = 16
+)
+
+// pauseState holds the events fired while the sends are paused.
+type pauseState struct {
+	mu     sync.Mutex
+	paused bool
+	events []pendingEvent
+}
+
+// pendingEvent is an event built by Fire and sent later.
+type pendingEvent struct {
+	entry       *logrus.Entry
+	client      *raven.Client
+	release     func() // called once the event is captured or dropped
+	packet      *raven.Packet
+	parts       []*raven.Packet
+	attachments []Attachment
+	finalizers  Finalizers
+}
+
+// PauseSends stops sending events until ResumeSends is called, so that the
+// logging path makes no outbound request during latency-critical windows,
+// e.g. a leader election. The events fired in between are buffered, up to
+// 1000 events; the next ones are dropped and counted in the DroppedByPause
+// stat.
+//
+// Events fired before the pause may still be in flight: call Flush before
+// PauseSends to wait for them.
+func (hook *SentryHook) PauseSends() {
+	hook.pause.mu.Lock()
+	hook.pause.paused = true
+	hook.pause.mu.Unlock()
+}
+
+// ResumeSends sends the events buffered since PauseSends, and the next ones
+// as usual. The buffered events are sent in the background, a few at a time
+// to not overflow the send queue of the client, and their send errors are
+// reported to the error handlers. Flush waits for them.
+func (hook *SentryHook) ResumeSends() {
+	hook.pause.mu.Lock()
+	events := hook.pause.events
+	hook.pause.events = nil
+	hook.pause.paused = false
+	hook.pause.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+
+	// Our use of hook.mu guarantees that we are following the WaitGroup rule
+	// of not calling Add in parallel with Wait.
+	hook.mu.RLock()
+	hook.wg.Add(1)
+	hook.stats.update(func(s *Stats) { s.Pending += int64(len(events)) })
+	hook.mu.RUnlock()
+
+	go func() {
+		defer hook.wg.Done()
+		var wg sync.WaitGroup
+		window := make(chan struct{}, resumeWindow)
+		for _, ev := range events {
+			window <- struct{}{}
+			wg.Add(1)
+			go func(ev pendingEvent) {
+				d := hook.sendPending(ev)
+				if d.Status == DeliveryFailed {
+					for _, handlerFn := range hook.errorHandlers {
+						handlerFn(ev.entry, d.Err)
+					}
+				}
+				hook.stats.update(func(s *Stats) { s.Pending-- })
+				<-window
+				hook.finalizeAfter(ev.finalizers, d, wg.Done)
+			}(ev)
+		}
+		wg.Wait()
+	}()
+}
+
+// sendPending sends a buffered or queued event and returns its outcome.
+func (hook *SentryHook) sendPending(ev pendingEvent) Delivery {
+	defer ev.release()
+	if len(ev.attachments) != 0 {
+		hook.setAttachments(ev.packet, ev.attachments)
+	}
+	var eventID string
+	var errCh chan error
+	if len(ev.parts) != 0 {
+		eventID, errCh = hook.captureParts(ev.client, ev.parts)
+	} else {
+		eventID, errCh = ev.client.Capture(ev.packet, nil)
+	}
+	if eventID == "" && len(errCh) == 0 {
+		// sampled out or excluded
+		hook.takeAttachments(ev.packet)
+		hook.returnConfigSnapshot(ev.packet, ev.parts)
+		return dropped(hook.countExcluded(ev.packet), nil)
+	}
+	return delivered(eventID, <-errCh)
+}
+
+// hold buffers the event if the sends are paused, and reports whether it
+// did, and whether it dropped the event instead as the buffer is full.
+func (p *pauseState) hold(ev pendingEvent, stats *hookStats) (held, dropped bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.paused {
+		return false, false
+	}
+	if len(p.events) >= maxPausedEvents {
+		stats.update(func(s *Stats) { s.DroppedByPause++ })
+		ev.release()
+		return true, true
+	}
+	p.events = append(p.events, ev)
+	return true, false
+}
@@ -0,0 +1,42 @@
+package logrus_sentry
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauseSends(t *testing.T) {
+	a := assert.New(t)
+
+	var requests int32
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		atomic.AddInt32(&requests, 1)
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+
+	hook.PauseSends()
+	for i := 0; i < maxPausedEvents+2; i++ {
+		logger.Error(message)
+	}
+	a.Equal(int32(0), atomic.LoadInt32(&requests), "no event must be sent while paused")
+	a.Equal(uint64(2), hook.Stats().DroppedByPause)
+
+	hook.ResumeSends()
+	hook.Flush()
+	a.Equal(int32(maxPausedEvents), atomic.LoadInt32(&requests), "buffered events must be sent on resume")
+
+	logger.Error(message)
+	a.Equal(int32(maxPausedEvents+1), atomic.LoadInt32(&requests), "events must be sent after resume")
+}
@@ -0,0 +1,218 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"github.com/musqdp/raven-go"
+)
+
+// QueuedEvent is an event of an asynchronous hook waiting in a Queue.
+type QueuedEvent struct {
+	ev pendingEvent
+}
+
+// Queue holds the events of an asynchronous hook until they are sent. Push is
+// called concurrently by the logging goroutines, and Pop by a single
+// goroutine of the hook.
+type Queue interface {
+	// Push adds ev to the queue, or returns false if the queue is full.
+	Push(ev *QueuedEvent) bool
+	// Pop removes and returns the oldest event, or returns nil if the queue
+	// is empty. It must not block.
+	Pop() *QueuedEvent
+}
+
+// queueDispatcher sends the events of a queue.
+type queueDispatcher struct {
+	queue   Queue
+	senders int
+	wake    chan struct{}
+	stop    chan struct{}
+}
+
+// SetQueue makes an asynchronous hook push its events to queue, from which
+// they are sent by up to senders goroutines at once, instead of handing them
+// directly to the send queue of the raven client, which holds 100 events.
+// It lets high-throughput applications absorb bursts of events by trading
+// memory for throughput. The events logged while the queue is full are
+// dropped and counted in the DroppedByQueue stat, and the async limit does
+// not apply. Flush waits for the queued events.
+//
+// It should be called when the hook is created, before any event. A nil
+// queue stops the queue once its events are sent.
+func (hook *SentryHook) SetQueue(queue Queue, senders int) error {
+	if queue != nil && (senders <= 0 || senders > raven.MaxQueueBuffer) {
+		return errors.New("senders must be between 1 and the raven queue size")
+	}
+	if d := hook.queue; d != nil {
+		close(d.stop)
+	}
+	hook.queue = nil
+	if queue == nil {
+		return nil
+	}
+	d := &queueDispatcher{
+		queue:   queue,
+		senders: senders,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}
+	hook.queue = d
+	go hook.dispatch(d)
+	return nil
+}
+
+// enqueue pushes ev to the queue of d, dropping it if the queue is full, and
+// reports whether it pushed it.
+func (hook *SentryHook) enqueue(d *queueDispatcher, ev pendingEvent) bool {
+	// Our use of hook.mu guarantees that we are following the WaitGroup rule
+	// of not calling Add in parallel with Wait. Add before the push, since
+	// the event may be sent at once.
+	hook.wg.Add(1)
+	hook.stats.update(func(s *Stats) { s.Pending++ })
+	if !d.queue.Push(&QueuedEvent{ev: ev}) {
+		ev.release()
+		hook.stats.update(func(s *Stats) {
+			s.Pending--
+			s.DroppedByQueue++
+		})
+		hook.wg.Done()
+		return false
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// dispatch sends the events of the queue of d until it is stopped and empty.
+func (hook *SentryHook) dispatch(d *queueDispatcher) {
+	window := make(chan struct{}, d.senders)
+	for {
+		qe := d.queue.Pop()
+		if qe == nil {
+			select {
+			case <-d.wake:
+				continue
+			case <-d.stop:
+				if qe = d.queue.Pop(); qe == nil {
+					return
+				}
+			}
+		}
+
+		window <- struct{}{}
+		go func(ev pendingEvent) {
+			d := hook.sendPending(ev)
+			if d.Status == DeliveryFailed {
+				for _, handlerFn := range hook.errorHandlers {
+					handlerFn(ev.entry, d.Err)
+				}
+			}
+			hook.stats.update(func(s *Stats) { s.Pending-- })
+			<-window
+			hook.finalizeAfter(ev.finalizers, d, hook.wg.Done)
+		}(qe.ev)
+	}
+}
+
+// channelQueue is a Queue backed by a buffered channel.
+type channelQueue chan *QueuedEvent
+
+// NewChannelQueue returns a Queue holding up to size events in a buffered
+// channel. It is the simplest queue, and the one to start with.
+func NewChannelQueue(size int) Queue {
+	return make(channelQueue, size)
+}
+
+// Push adds ev to the channel, or returns false if it is full.
+func (q channelQueue) Push(ev *QueuedEvent) bool {
+	select {
+	case q <- ev:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pop removes the oldest event from the channel, or returns nil.
+func (q channelQueue) Pop() *QueuedEvent {
+	select {
+	case ev := <-q:
+		return ev
+	default:
+		return nil
+	}
+}
+
+// ringQueue is a lock-free bounded ring buffer with multiple producers and
+// a single consumer, in the style of the LMAX disruptor: each slot has a
+// sequence number telling whether it is free for the push of a position, or
+// holds the event of a position.
+type ringQueue struct {
+	head uint64 // next position to push, shared by the producers
+	_    [56]byte
+	tail uint64 // next position to pop, owned by the consumer
+	_    [56]byte
+
+	mask   uint64
+	seqs   []uint64
+	events []*QueuedEvent
+}
+
+// NewRingQueue returns a lock-free Queue holding up to size events, rounded
+// up to a power of two. It scales better than NewChannelQueue when many
+// goroutines log at once.
+func NewRingQueue(size int) Queue {
+	n := 1
+	for n < size {
+		n <<= 1
+	}
+	q := &ringQueue{
+		mask:   uint64(n - 1),
+		seqs:   make([]uint64, n),
+		events: make([]*QueuedEvent, n),
+	}
+	for i := range q.seqs {
+		q.seqs[i] = uint64(i)
+	}
+	return q
+}
+
+// Push claims the slot of the next position, or returns false if it still
+// holds the event of the previous lap.
+func (q *ringQueue) Push(ev *QueuedEvent) bool {
+	for {
+		pos := atomic.LoadUint64(&q.head)
+		i := pos & q.mask
+		seq := atomic.LoadUint64(&q.seqs[i])
+		switch {
+		case seq == pos:
+			if atomic.CompareAndSwapUint64(&q.head, pos, pos+1) {
+				q.events[i] = ev
+				atomic.StoreUint64(&q.seqs[i], pos+1)
+				return true
+			}
+		case seq < pos:
+			return false
+		}
+		// another producer claimed the slot: try the next position
+	}
+}
+
+// Pop takes the event of the next position, if it was pushed, and frees its
+// slot for the next lap.
+func (q *ringQueue) Pop() *QueuedEvent {
+	pos := q.tail
+	i := pos & q.mask
+	if atomic.LoadUint64(&q.seqs[i]) != pos+1 {
+		return nil
+	}
+	ev := q.events[i]
+	q.events[i] = nil
+	atomic.StoreUint64(&q.seqs[i], pos+q.mask+1)
+	q.tail = pos + 1
+	return ev
+}
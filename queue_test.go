@@ -0,0 +1,107 @@
+package logrus_sentry
+
+import (
+	"net/http"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueues(t *testing.T) {
+	for name, newQueue := range map[string]func(int) Queue{
+		"channel": NewChannelQueue,
+		"ring":    NewRingQueue,
+	} {
+		t.Run(name, func(t *testing.T) {
+			a := assert.New(t)
+
+			q := newQueue(4)
+			a.Nil(q.Pop(), "an empty queue should pop nil")
+			events := make([]*QueuedEvent, 10)
+			for i := range events {
+				events[i] = &QueuedEvent{}
+			}
+			// several laps
+			for lap := 0; lap < 3; lap++ {
+				for _, ev := range events[:4] {
+					a.True(q.Push(ev))
+				}
+				a.False(q.Push(events[4]), "a full queue should refuse events")
+				for _, ev := range events[:4] {
+					a.True(ev == q.Pop(), "events should be popped in order")
+				}
+				a.Nil(q.Pop())
+			}
+		})
+	}
+}
+
+func TestRingQueueConcurrency(t *testing.T) {
+	a := assert.New(t)
+
+	q := NewRingQueue(64)
+	const producers, perProducer = 8, 1000
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				for !q.Push(&QueuedEvent{}) {
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	popped := 0
+	for popped < producers*perProducer {
+		if q.Pop() != nil {
+			popped++
+		} else {
+			runtime.Gosched()
+		}
+	}
+	wg.Wait()
+	a.Nil(q.Pop())
+}
+
+func TestSetQueue(t *testing.T) {
+	a := assert.New(t)
+
+	var received int32
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&received, 1)
+	})
+	defer s.Close()
+
+	hook, err := NewAsyncSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewAsyncSentryHook should be NoError")
+	a.Error(hook.SetQueue(NewRingQueue(1024), 0), "senders should be positive")
+	a.NoError(hook.SetQueue(NewRingQueue(1024), 8))
+	defer hook.SetQueue(nil, 0)
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	// more than the raven queue holds
+	for i := 0; i < 500; i++ {
+		logger.Error(message)
+	}
+	hook.Flush()
+	a.Equal(int32(500), atomic.LoadInt32(&received), "the queue should absorb the burst")
+	stats := hook.Stats()
+	a.Equal(uint64(0), stats.DroppedByQueue)
+	a.Equal(int64(0), stats.Pending)
+
+	a.NoError(hook.SetQueue(NewChannelQueue(1), 1))
+	hook.PauseSends()
+	logger.Error(message)
+	hook.ResumeSends()
+	hook.Flush()
+	a.Equal(int32(501), atomic.LoadInt32(&received), "paused events should be sent on resume")
+}
@@ -0,0 +1,170 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const regionProbeTimeout = 5 * time.Second
+
+// regionSelector periodically probes the regional endpoints of a project and
+// points the client to the fastest healthy one.
+type regionSelector struct {
+	client   *raven.Client
+	dsns     []string
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu         sync.RWMutex // guards httpClient and selected
+	httpClient *http.Client
+	selected   string
+}
+
+// SetRegionalDSNs configures several regional ingestion endpoints of the same
+// project. They are probed every interval, and events are sent to the healthy
+// endpoint with the lowest latency. Until the first probe completes, events
+// are sent to the first DSN.
+func (hook *SentryHook) SetRegionalDSNs(dsns []string, interval time.Duration) error {
+	if len(dsns) == 0 {
+		return errors.New("no regional DSN")
+	}
+	if interval <= 0 {
+		return errors.New("probe interval must be positive")
+	}
+	for _, dsn := range dsns {
+		if _, err := raven.New(dsn); err != nil {
+			return err
+		}
+	}
+	if err := hook.client.SetDSN(dsns[0]); err != nil {
+		return err
+	}
+
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if r := hook.regions; r != nil {
+		r.close()
+	}
+	r := &regionSelector{
+		client:     hook.client,
+		dsns:       dsns,
+		httpClient: hook.probeHTTPClient(),
+		stop:       make(chan struct{}),
+		selected:   dsns[0],
+	}
+	hook.regions = r
+	go r.run(interval)
+	return nil
+}
+
+// StopRegionalProbes stops probing the regional DSNs, e.g. before the hook is
+// discarded. The events keep being sent to the DSN selected last.
+func (hook *SentryHook) StopRegionalProbes() {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	if r := hook.regions; r != nil {
+		r.close()
+	}
+}
+
+// RegionalDSN returns the regional DSN events are currently sent to, or an
+// empty string if no regional DSNs are configured.
+func (hook *SentryHook) RegionalDSN() string {
+	if hook.regions == nil {
+		return ""
+	}
+	r := hook.regions
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.selected
+}
+
+func (r *regionSelector) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.probe()
+		select {
+		case <-ticker.C:
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// close stops the probes.
+func (r *regionSelector) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// probe measures the latency of every endpoint and selects the fastest
+// healthy one. The selection is kept if no endpoint is healthy.
+func (r *regionSelector) probe() {
+	type result struct {
+		dsn     string
+		latency time.Duration
+		ok      bool
+	}
+
+	r.mu.RLock()
+	httpClient := r.httpClient
+	r.mu.RUnlock()
+
+	results := make([]result, len(r.dsns))
+	var wg sync.WaitGroup
+	for i, dsn := range r.dsns {
+		wg.Add(1)
+		go func(i int, dsn string) {
+			defer wg.Done()
+			latency, ok := measure(httpClient, dsn)
+			results[i] = result{dsn, latency, ok}
+		}(i, dsn)
+	}
+	wg.Wait()
+
+	best := -1
+	for i, res := range results {
+		if res.ok && (best < 0 || res.latency < results[best].latency) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return
+	}
+
+	dsn := results[best].dsn
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if dsn == r.selected {
+		return
+	}
+	if err := r.client.SetDSN(dsn); err == nil {
+		r.selected = dsn
+	}
+}
+
+// measure returns the round trip time of a request to the endpoint root.
+// Any response but a server error means the endpoint is healthy.
+func measure(httpClient *http.Client, dsn string) (time.Duration, bool) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return 0, false
+	}
+	target := u.Scheme + "://" + u.Host + "/"
+
+	start := time.Now()
+	res, err := httpClient.Head(target)
+	if err != nil {
+		return 0, false
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+	return time.Since(start), res.StatusCode < http.StatusInternalServerError
+}
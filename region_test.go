@@ -0,0 +1,91 @@
+package logrus_sentry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetRegionalDSNs(t *testing.T) {
+	a := assert.New(t)
+
+	slow, slowDSN := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		if req.Method == http.MethodHead {
+			time.Sleep(200 * time.Millisecond)
+		}
+	})
+	defer slow.Close()
+
+	received := make(chan struct{}, 1)
+	fast, fastDSN := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		if req.Method == http.MethodPost {
+			received <- struct{}{}
+		}
+	})
+	defer fast.Close()
+
+	down, downDSN := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer down.Close()
+
+	hook, err := NewSentryHook(slowDSN, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.Equal("", hook.RegionalDSN())
+
+	a.Error(hook.SetRegionalDSNs(nil, time.Hour), "empty DSNs should be an error")
+	a.Error(hook.SetRegionalDSNs([]string{"invalid dsn"}, time.Hour), "invalid DSN should be an error")
+	a.Error(hook.SetRegionalDSNs([]string{slowDSN}, 0), "zero interval should be an error")
+
+	a.NoError(hook.SetRegionalDSNs([]string{slowDSN, downDSN, fastDSN}, time.Hour))
+	a.Equal(slowDSN, hook.RegionalDSN(), "first DSN should be used until probed")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hook.RegionalDSN() != fastDSN && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	a.Equal(fastDSN, hook.RegionalDSN(), "fastest healthy DSN should be selected")
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Error("event should be sent to the selected DSN")
+	}
+}
+
+func TestStopRegionalProbes(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.NotPanics(hook.StopRegionalProbes, "stopping without regional DSNs should not panic")
+	a.NoError(hook.SetRegionalDSNs([]string{dsn, dsn}, time.Hour))
+	r := hook.regions
+
+	hook.StopRegionalProbes()
+	select {
+	case <-r.stop:
+	default:
+		t.Error("the probes should be stopped")
+	}
+	a.Equal(dsn, hook.RegionalDSN(), "the selected DSN should be kept")
+	a.NotPanics(hook.StopRegionalProbes, "stopping twice should not panic")
+}
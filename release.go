@@ -0,0 +1,42 @@
+package logrus_sentry
+
+import (
+	"os"
+)
+
+const (
+	envRelease     = "SENTRY_RELEASE"
+	envEnvironment = "SENTRY_ENVIRONMENT"
+	envDist        = "SENTRY_DIST"
+)
+
+// distInterface sets the dist attribute of a packet, which raven.Packet
+// has no field for.
+type distInterface string
+
+func (distInterface) Class() string { return "dist" }
+
+// SetDist sets the distribution of the release, e.g. a build number.
+func (hook *SentryHook) SetDist(dist string) {
+	hook.dist = dist
+}
+
+// DetectRelease sets the release, environment and dist from the
+// SENTRY_RELEASE, SENTRY_ENVIRONMENT and SENTRY_DIST environment variables.
+// Without SENTRY_RELEASE, the release is the VCS revision stamped in the
+// binary by the go toolchain, suffixed with "-dirty" for modified trees.
+func (hook *SentryHook) DetectRelease() {
+	release := os.Getenv(envRelease)
+	if release == "" {
+		release = vcsRelease()
+	}
+	if release != "" {
+		hook.SetRelease(release)
+	}
+	if environment := os.Getenv(envEnvironment); environment != "" {
+		hook.SetEnvironment(environment)
+	}
+	if dist := os.Getenv(envDist); dist != "" {
+		hook.SetDist(dist)
+	}
+}
@@ -0,0 +1,30 @@
+//go:build go1.18
+// +build go1.18
+
+package logrus_sentry
+
+import (
+	"runtime/debug"
+)
+
+// vcsRelease returns the VCS revision stamped in the binary.
+func vcsRelease() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision string
+	var modified bool
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			revision = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
@@ -0,0 +1,10 @@
+//go:build !go1.18
+// +build !go1.18
+
+package logrus_sentry
+
+// vcsRelease returns the VCS revision stamped in the binary, which is only
+// available since go1.18.
+func vcsRelease() string {
+	return ""
+}
@@ -0,0 +1,66 @@
+package logrus_sentry
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDist(t *testing.T) {
+	const dist = "42"
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetDist(dist)
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		a.Equal(dist, packet.Dist, "dist must be set")
+	})
+}
+
+func TestDetectRelease(t *testing.T) {
+	a := assert.New(t)
+
+	for key, value := range map[string]string{
+		envRelease:     "v1.2.3",
+		envEnvironment: "staging",
+		envDist:        "7",
+	} {
+		old, ok := os.LookupEnv(key)
+		os.Setenv(key, value)
+		if ok {
+			defer os.Setenv(key, old)
+		} else {
+			defer os.Unsetenv(key)
+		}
+	}
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetRelease("")
+		hook.SetEnvironment("")
+		hook.DetectRelease()
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		a.Equal("v1.2.3", packet.Release, "release must be detected")
+		a.Equal("staging", packet.Environment, "environment must be detected")
+		a.Equal("7", packet.Dist, "dist must be detected")
+	})
+}
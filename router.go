@@ -0,0 +1,153 @@
+package logrus_sentry
+
+import (
+	"errors"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Route sends the entries matching its predicate to a dedicated sentry project.
+// Either DSN or Client must be set; Client takes precedence.
+type Route struct {
+	DSN    string
+	Client *raven.Client
+	Match  func(entry *logrus.Entry) bool
+}
+
+type route struct {
+	dsn    string
+	client *raven.Client
+	match  func(entry *logrus.Entry) bool
+}
+
+// destination is where the event of an entry is sent: the client of a route,
+// of a DSN resolved for the entry, or the default client.
+type destination struct {
+	route *route
+	// dsn is the DSN resolved for the entry
+	dsn string
+}
+
+// NewRoutingSentryHook creates a hook which sends each entry to the client of
+// the first route whose predicate matches it. Entries matching no route are
+// sent to fallbackDSN.
+func NewRoutingSentryHook(fallbackDSN string, routes []Route, levels []logrus.Level) (*SentryHook, error) {
+	hook, err := NewSentryHook(fallbackDSN, levels)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range routes {
+		if r.Match == nil {
+			return nil, errors.New("route has no Match function")
+		}
+		client := r.Client
+		if client == nil {
+			if r.DSN == "" {
+				return nil, errors.New("route has neither DSN nor Client")
+			}
+			client, err = raven.New(r.DSN)
+			if err != nil {
+				return nil, err
+			}
+		}
+		hook.instrumentClient(client)
+		hook.routes = append(hook.routes, route{
+			dsn:    r.DSN,
+			client: client,
+			match:  r.Match,
+		})
+	}
+	return hook, nil
+}
+
+// NewAsyncRoutingSentryHook creates a hook same as NewRoutingSentryHook, but
+// in asynchronous mode.
+func NewAsyncRoutingSentryHook(fallbackDSN string, routes []Route, levels []logrus.Level) (*SentryHook, error) {
+	hook, err := NewRoutingSentryHook(fallbackDSN, routes, levels)
+	return setAsync(hook), err
+}
+
+// clientSetting is a setting of the clients of the hook, applied again to
+// the clients created later.
+type clientSetting struct {
+	name  string
+	apply func(client *raven.Client) error
+}
+
+// destination returns the first route matching entry, or the DSN resolved
+// for entry, or the default client.
+func (hook *SentryHook) destination(entry *logrus.Entry) destination {
+	for i := range hook.routes {
+		if hook.routes[i].match(entry) {
+			return destination{route: &hook.routes[i]}
+		}
+	}
+	if t := hook.tenants; t != nil {
+		if dsn, ok := t.resolve(entry); ok && dsn != "" {
+			return destination{dsn: dsn}
+		}
+	}
+	return destination{}
+}
+
+// acquireClient returns the client of the destination of entry, and a
+// function to call once entry is captured.
+func (hook *SentryHook) acquireClient(entry *logrus.Entry) (*raven.Client, func()) {
+	return hook.acquireDestination(entry, hook.destination(entry))
+}
+
+// acquireDestination returns the client of d, and a function to call once
+// entry is captured.
+func (hook *SentryHook) acquireDestination(entry *logrus.Entry, d destination) (*raven.Client, func()) {
+	if d.route != nil {
+		return d.route.client, func() {}
+	}
+	if t := hook.tenants; t != nil && d.dsn != "" {
+		tc, err := hook.tenantClient(t, d.dsn)
+		if err == nil {
+			return tc.client, func() { t.release(tc) }
+		}
+		for _, handlerFn := range hook.errorHandlers {
+			handlerFn(entry, err)
+		}
+	}
+	return hook.client, func() {}
+}
+
+// clients returns the default client followed by the clients of all routes
+// and the cached clients of the resolved DSNs.
+func (hook *SentryHook) clients() []*raven.Client {
+	clients := make([]*raven.Client, 0, len(hook.routes)+1)
+	clients = append(clients, hook.client)
+	for _, r := range hook.routes {
+		clients = append(clients, r.client)
+	}
+	if t := hook.tenants; t != nil {
+		clients = append(clients, t.cached()...)
+	}
+	return clients
+}
+
+// configureClients applies fn to the clients of the hook, and records it
+// under name, replacing the previous setting of the same name, so that it is
+// applied to the clients created later.
+func (hook *SentryHook) configureClients(name string, fn func(client *raven.Client) error) error {
+	hook.settingsMu.Lock()
+	defer hook.settingsMu.Unlock()
+
+	for _, client := range hook.clients() {
+		if err := fn(client); err != nil {
+			return err
+		}
+	}
+	settings := hook.clientSettings[:0:0]
+	for _, s := range hook.clientSettings {
+		if s.name != name {
+			settings = append(settings, s)
+		}
+	}
+	hook.clientSettings = append(settings, clientSetting{name: name, apply: fn})
+	return nil
+}
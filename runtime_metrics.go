@@ -0,0 +1,47 @@
+package logrus_sentry
+
+import (
+	"sync"
+	"time"
+)
+
+// contextGoRuntime is the context holding the runtime metrics.
+const contextGoRuntime = "go_runtime"
+
+// runtimeMetrics caches a snapshot of the runtime metrics.
+type runtimeMetrics struct {
+	interval time.Duration
+
+	mu       sync.Mutex
+	sampled  time.Time
+	snapshot map[string]interface{}
+}
+
+// EnableRuntimeMetrics adds a go_runtime context to the events of the error
+// level and above, with a few runtime metrics: the 99th percentile of the GC
+// pauses and of the scheduling latencies, the live heap size and the number
+// of goroutines. The metrics are read at most once per interval, and the
+// events in between share the last snapshot.
+//
+// The metrics are only available since go1.17.
+func (hook *SentryHook) EnableRuntimeMetrics(interval time.Duration) {
+	hook.runtimeMetrics = &runtimeMetrics{interval: interval}
+}
+
+// get returns the snapshot of the metrics, reading them if the last one is
+// older than the interval.
+func (m *runtimeMetrics) get(now time.Time) map[string]interface{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.snapshot == nil || now.Sub(m.sampled) >= m.interval {
+		snapshot := readRuntimeMetrics()
+		if snapshot == nil {
+			return nil
+		}
+		snapshot["sampled_at"] = now.UTC().Format(time.RFC3339Nano)
+		m.snapshot = snapshot
+		m.sampled = now
+	}
+	return m.snapshot
+}
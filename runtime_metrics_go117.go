@@ -0,0 +1,92 @@
+//go:build go1.17
+// +build go1.17
+
+package logrus_sentry
+
+import (
+	"math"
+	"runtime/metrics"
+	"sync"
+)
+
+// runtimeMetricNames maps the keys of the go_runtime context to the names of
+// the metrics they are read from, in order of preference, as some were
+// renamed or added by later go versions.
+var runtimeMetricNames = []struct {
+	key   string
+	names []string
+}{
+	{"gc_pause_p99_seconds", []string{"/sched/pauses/total/gc:seconds", "/gc/pauses:seconds"}},
+	{"sched_latency_p99_seconds", []string{"/sched/latencies:seconds"}},
+	{"heap_live_bytes", []string{"/gc/heap/live:bytes", "/memory/classes/heap/objects:bytes"}},
+	{"goroutines", []string{"/sched/goroutines:goroutines"}},
+}
+
+var (
+	runtimeMetricsOnce sync.Once
+	runtimeMetricKeys  []string
+	runtimeSamples     []metrics.Sample
+)
+
+// initRuntimeMetrics selects the supported metric of each key.
+func initRuntimeMetrics() {
+	supported := make(map[string]bool)
+	for _, d := range metrics.All() {
+		supported[d.Name] = true
+	}
+	for _, m := range runtimeMetricNames {
+		for _, name := range m.names {
+			if supported[name] {
+				runtimeMetricKeys = append(runtimeMetricKeys, m.key)
+				runtimeSamples = append(runtimeSamples, metrics.Sample{Name: name})
+				break
+			}
+		}
+	}
+}
+
+// readRuntimeMetrics returns the current runtime metrics.
+func readRuntimeMetrics() map[string]interface{} {
+	runtimeMetricsOnce.Do(initRuntimeMetrics)
+
+	samples := make([]metrics.Sample, len(runtimeSamples))
+	copy(samples, runtimeSamples)
+	metrics.Read(samples)
+
+	snapshot := make(map[string]interface{}, len(samples))
+	for i, s := range samples {
+		switch s.Value.Kind() {
+		case metrics.KindUint64:
+			snapshot[runtimeMetricKeys[i]] = s.Value.Uint64()
+		case metrics.KindFloat64:
+			snapshot[runtimeMetricKeys[i]] = s.Value.Float64()
+		case metrics.KindFloat64Histogram:
+			snapshot[runtimeMetricKeys[i]] = percentile(s.Value.Float64Histogram(), 0.99)
+		}
+	}
+	return snapshot
+}
+
+// percentile returns the upper bound of the bucket of h holding the
+// percentile p.
+func percentile(h *metrics.Float64Histogram, p float64) float64 {
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(float64(total) * p))
+	var cumulated uint64
+	for i, c := range h.Counts {
+		cumulated += c
+		if cumulated >= rank {
+			if upper := h.Buckets[i+1]; !math.IsInf(upper, 1) {
+				return upper
+			}
+			return h.Buckets[i]
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1]
+}
@@ -0,0 +1,10 @@
+//go:build !go1.17
+// +build !go1.17
+
+package logrus_sentry
+
+// readRuntimeMetrics returns the current runtime metrics, which are only
+// available since go1.17.
+func readRuntimeMetrics() map[string]interface{} {
+	return nil
+}
@@ -0,0 +1,62 @@
+//go:build go1.17
+// +build go1.17
+
+package logrus_sentry
+
+import (
+	"math"
+	"runtime/metrics"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableRuntimeMetrics(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.EnableRuntimeMetrics(time.Hour)
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		snapshot, ok := packet.Contexts[contextGoRuntime].(map[string]interface{})
+		if a.True(ok, "go_runtime context must be set") {
+			a.Contains(snapshot, "gc_pause_p99_seconds")
+			a.Contains(snapshot, "sched_latency_p99_seconds")
+			a.Contains(snapshot, "heap_live_bytes")
+			a.NotZero(snapshot["goroutines"])
+		}
+
+		logger.Error(message)
+		packet = <-pch
+		a.Equal(snapshot["sampled_at"], packet.Contexts[contextGoRuntime].(map[string]interface{})["sampled_at"],
+			"the snapshot must be reused within the interval")
+
+		logger.Warn(message)
+		packet = <-pch
+		a.NotContains(packet.Contexts, contextGoRuntime, "warnings must not carry the runtime metrics")
+	})
+}
+
+func TestPercentile(t *testing.T) {
+	a := assert.New(t)
+
+	h := &metrics.Float64Histogram{
+		Counts:  []uint64{90, 9, 1},
+		Buckets: []float64{0, 1, 2, math.Inf(1)},
+	}
+	a.Equal(float64(1), percentile(h, 0.5))
+	a.Equal(float64(2), percentile(h, 0.99))
+	a.Equal(float64(2), percentile(h, 1), "an infinite bucket must report its lower bound")
+	a.Equal(float64(0), percentile(&metrics.Float64Histogram{Counts: []uint64{0}, Buckets: []float64{0, 1}}, 0.99))
+}
@@ -3,6 +3,9 @@ package logrus_sentry
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
 	"runtime"
 	"sync"
 	"time"
@@ -24,32 +27,91 @@ var (
 	}
 )
 
+const (
+	// tagStacktraceFailure is the tag set on events whose stacktrace could not
+	// be captured.
+	tagStacktraceFailure = "stacktrace_failure"
+
+	stacktraceFailurePanic         = "panic"
+	stacktraceFailureInvalidFrames = "invalid_frames"
+)
+
 // SentryHook delivers logs to a sentry server.
 type SentryHook struct {
 	// Timeout sets the time to wait for a delivery error from the sentry server.
 	// If this is set to zero the server will not wait for any response and will
 	// consider the message correctly sent.
 	//
+	// The wait also stops when the context of the entry is done.
+	//
 	// This is ignored for asynchronous hooks. If you want to set a timeout when
 	// using an async hook (to bound the length of time that hook.Flush can take),
-	// you probably want to create your own raven.Client and set
-	// ravenClient.Transport.(*raven.HTTPTransport).Client.Timeout to set a
-	// timeout on the underlying HTTP request instead.
+	// you probably want to set an HTTP client with a timeout using
+	// hook.SetHTTPClient to set a timeout on the underlying HTTP request instead.
 	Timeout                 time.Duration
 	StacktraceConfiguration StackTraceConfiguration
 
-	client *raven.Client
-	levels []logrus.Level
-
-	serverName    string
-	ignoreFields  map[string]struct{}
-	extraFilters  map[string]func(interface{}) interface{}
-	errorHandlers []func(entry *logrus.Entry, err error)
+	client  *raven.Client
+	routes  []route
+	tenants *tenantClients
+	levels  []logrus.Level
+
+	settingsMu     sync.Mutex // guards clientSettings and the creation of clients
+	clientSettings []clientSetting
+
+	serverName        string
+	dist              string
+	severityMap       map[logrus.Level]raven.Severity
+	levelTags         map[logrus.Level]map[string]string
+	levelFingerprints map[logrus.Level][]string
+	eventIDGenerator  func() string
+	traceExtractor    TraceExtractor
+	deployment        *deploymentCache
+	fingerprintRules  []fingerprintRule
+	thresholds        []callSiteThreshold
+	callSites         callSiteCounter
+	throttle          *throttle
+	throttleStore     *throttlePersistence
+	runtimeMetrics    *runtimeMetrics
+	splitting         *eventSplitting
+	crashLoop         *crashLoop
+	userFields        UserFieldMapping
+	ignoreFields      map[string]struct{}
+	extraFilters      map[string]func(interface{}) interface{}
+	extraSizeLimit    int
+	largeExtras       *largeExtras
+	escaping          Escaping
+	dsnEscaping       map[string]Escaping
+	goroutineInfo     bool
+	ignoreErrors      *regexp.Regexp
+	health            *healthReport
+	extraKeyLimits    map[string]int
+	dropFuncFields    bool
+	configSnapshot    bool
+	errorHandlers     []func(entry *logrus.Entry, err error)
+
+	attachmentExtractors []func(entry *logrus.Entry) []Attachment
+	attachments          sync.Map // event ID to []Attachment
 
 	asynchronous bool
-
-	mu sync.RWMutex
-	wg sync.WaitGroup
+	asyncLimit   *asyncLimit
+	queue        *queueDispatcher
+	strict       bool
+	repanic      bool
+	sampleRate   float32
+	// sampleRateSet is whether sampleRate was set by SetSampleRate
+	sampleRateSet bool
+	spool         *spool
+	regions       *regionSelector
+	stats         hookStats
+	pause         pauseState
+	httpClient    *http.Client
+
+	configSnapshotSent uint32
+
+	mu         sync.RWMutex
+	wg         sync.WaitGroup
+	finalizing finalizerGroup
 }
 
 // The Stacktracer interface allows an error type to return a raven.Stacktrace.
@@ -83,8 +145,18 @@ type StackTraceConfiguration struct {
 	SendExceptionType bool
 	// whether the exception type and message should be switched.
 	SwitchExceptionTypeAndMessage bool
+	// whether the exception of an error chain should have the type of the
+	// root cause, and a value made of the outermost and root messages
+	PrettyExceptionTitles bool
 	// whether to include a breadcrumb with the full error stack
 	IncludeErrorBreadcrumb bool
+	// whether frames of the runtime package should be skipped
+	SkipRuntimeFrames bool
+	// whether frames of vendored packages should be skipped
+	SkipVendorFrames bool
+	// whether the module cache directory and module versions should be
+	// trimmed from file names. It changes the grouping of existing issues.
+	TrimModulePaths bool
 }
 
 // NewSentryHook creates a hook to be added to an instance of logger
@@ -116,7 +188,7 @@ func NewWithTagsSentryHook(DSN string, tags map[string]string, levels []logrus.L
 // NewWithClientSentryHook creates a hook using an initialized raven client.
 // This method sets the timeout to 100 milliseconds.
 func NewWithClientSentryHook(client *raven.Client, levels []logrus.Level) (*SentryHook, error) {
-	return &SentryHook{
+	hook := &SentryHook{
 		Timeout: 100 * time.Millisecond,
 		StacktraceConfiguration: StackTraceConfiguration{
 			Enable:            false,
@@ -126,11 +198,14 @@ func NewWithClientSentryHook(client *raven.Client, levels []logrus.Level) (*Sent
 			InAppPrefixes:     nil,
 			SendExceptionType: true,
 		},
-		client:       client,
-		levels:       levels,
-		ignoreFields: make(map[string]struct{}),
-		extraFilters: make(map[string]func(interface{}) interface{}),
-	}, nil
+		client:           client,
+		levels:           levels,
+		eventIDGenerator: newEventID,
+		ignoreFields:     make(map[string]struct{}),
+		extraFilters:     make(map[string]func(interface{}) interface{}),
+	}
+	hook.instrumentClient(client)
+	return hook, nil
 }
 
 // NewAsyncSentryHook creates a hook same as NewSentryHook, but in asynchronous
@@ -165,12 +240,45 @@ func setAsync(hook *SentryHook) *SentryHook {
 // Fire is called when an event should be sent to sentry
 // Special fields that sentry uses to give more information to the server
 // are extracted from entry.Data (if they are found)
-// These fields are: error, logger, server_name, http_request, tags
+// These fields are: error, logger, server_name, http_request, tags, user
 func (hook *SentryHook) Fire(entry *logrus.Entry) error {
+	var out outcome
+	err := hook.fire(entry, &out)
+	// once hook.mu is released, since the finalizers may log
+	out.finish()
+	return err
+}
+
+// fireAndReport fires entry for the callers which cannot return the error of
+// Fire, and passes it to the error handlers unless Fire already did, as for
+// the send errors.
+func (hook *SentryHook) fireAndReport(entry *logrus.Entry) {
+	var out outcome
+	err := hook.fire(entry, &out)
+	out.finish()
+	if err == nil || (out.d != nil && out.d.Status == DeliveryFailed) {
+		return
+	}
+	for _, handlerFn := range hook.errorHandlers {
+		handlerFn(entry, err)
+	}
+}
+
+// fire sends the event of entry, and records in out its outcome if it is
+// known before fire returns.
+func (hook *SentryHook) fire(entry *logrus.Entry, out *outcome) error {
 	hook.mu.RLock() // Allow multiple go routines to log simultaneously
 	defer hook.mu.RUnlock()
 
 	df := newDataField(entry.Data)
+	finalizers := df.getFinalizers(entry)
+	out.fins = finalizers
+
+	if len(hook.thresholds) != 0 && !hook.allowCallSite(entry) {
+		hook.stats.update(func(s *Stats) { s.DroppedByThreshold++ })
+		out.drop(DropThreshold, nil)
+		return nil
+	}
 
 	err, hasError := df.getError()
 	var crumbs *Breadcrumbs
@@ -186,13 +294,16 @@ func (hook *SentryHook) Fire(entry *logrus.Entry) error {
 
 	packet := raven.NewPacketWithExtra(entry.Message, nil, crumbs)
 	packet.Timestamp = raven.Timestamp(entry.Time)
-	packet.Level = severityMap[entry.Level]
+	packet.Level = hook.severity(entry.Level)
 	packet.Platform = "go"
 
 	// set special fields
 	if hook.serverName != "" {
 		packet.ServerName = hook.serverName
 	}
+	if hook.dist != "" {
+		packet.Interfaces = append(packet.Interfaces, distInterface(hook.dist))
+	}
 	if logger, ok := df.getLogger(); ok {
 		packet.Logger = logger
 	}
@@ -201,38 +312,83 @@ func (hook *SentryHook) Fire(entry *logrus.Entry) error {
 	}
 	if eventID, ok := df.getEventID(); ok {
 		packet.EventID = eventID
+	} else if hook.eventIDGenerator != nil {
+		packet.EventID = hook.eventIDGenerator()
 	}
 	if tags, ok := df.getTags(); ok {
 		packet.Tags = tags
 	}
 	if fingerprint, ok := df.getFingerprint(); ok {
 		packet.Fingerprint = fingerprint
+	} else if fingerprint, ok := hook.ruleFingerprint(entry); ok {
+		packet.Fingerprint = fingerprint
+	} else if fingerprint, ok := hook.levelFingerprints[entry.Level]; ok {
+		packet.Fingerprint = fingerprint
+	}
+	if tags, ok := hook.levelTags[entry.Level]; ok {
+		packet.AddTags(tags)
 	}
 	if req, ok := df.getHTTPRequest(); ok {
 		packet.Interfaces = append(packet.Interfaces, req)
 	}
-	if user, ok := df.getUser(); ok {
+	attachments := hook.entryAttachments(df, entry)
+	user, hasUser := df.getUserWithMapping(hook.userFields)
+	if hasUser {
 		packet.Interfaces = append(packet.Interfaces, user)
 	}
+	if entry.Context != nil {
+		applyContext(entry.Context, packet, hasUser)
+		applyLabels(entry.Context, packet)
+		hook.applyTrace(entry.Context, packet)
+	}
+	if hook.deployment != nil {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		hook.applyDeployment(packet, now)
+	}
+	if m := hook.runtimeMetrics; m != nil && entry.Level <= logrus.ErrorLevel {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if snapshot := m.get(now); snapshot != nil {
+			setPacketContext(packet, contextGoRuntime, snapshot)
+		}
+	}
+	if contexts, ok := df.getContexts(); ok {
+		for name, value := range contexts {
+			setPacketContext(packet, name, value)
+		}
+	}
 
 	// set stacktrace data
 	stConfig := &hook.StacktraceConfiguration
 	if stConfig.Enable && entry.Level <= stConfig.Level {
 		if err, ok := df.getError(); ok {
-			var currentStacktrace *raven.Stacktrace
-			currentStacktrace = hook.findStacktrace(err)
-			if currentStacktrace == nil {
+			currentStacktrace, failure := captureStacktrace(func() *raven.Stacktrace {
+				return hook.findStacktrace(err)
+			})
+			if currentStacktrace == nil && failure == "" {
 				currentStacktrace = raven.NewStacktrace(stConfig.Skip, stConfig.Context, stConfig.InAppPrefixes)
 			}
-			cause := errors.Cause(err)
-			if cause == nil {
-				cause = err
+			currentStacktrace = hook.processStacktrace(currentStacktrace)
+			if failure != "" {
+				packet.Tags = append(packet.Tags, raven.Tag{Key: tagStacktraceFailure, Value: failure})
+			}
+			cause := causeOf(err)
+			if stConfig.PrettyExceptionTitles {
+				cause = rootCause(err)
 			}
 			exc := raven.NewException(cause, currentStacktrace)
+			if stConfig.PrettyExceptionTitles {
+				exc.Value = prettyExceptionValue(err, cause)
+			}
 			if !stConfig.SendExceptionType {
 				exc.Type = ""
 			}
-			if stConfig.SwitchExceptionTypeAndMessage {
+			if stConfig.SwitchExceptionTypeAndMessage && currentStacktrace != nil {
 				packet.Interfaces = append(packet.Interfaces, currentStacktrace)
 				packet.Culprit = exc.Type + ": " + currentStacktrace.Culprit()
 			} else {
@@ -241,6 +397,7 @@ func (hook *SentryHook) Fire(entry *logrus.Entry) error {
 			}
 		} else {
 			currentStacktrace := raven.NewStacktrace(stConfig.Skip, stConfig.Context, stConfig.InAppPrefixes)
+			currentStacktrace = hook.processStacktrace(currentStacktrace)
 			if currentStacktrace != nil {
 				packet.Interfaces = append(packet.Interfaces, currentStacktrace)
 			}
@@ -249,11 +406,31 @@ func (hook *SentryHook) Fire(entry *logrus.Entry) error {
 		// set the culprit even when the stack trace is disabled, as long as we have an error
 		if err, ok := df.getError(); ok {
 			packet.Culprit = err.Error()
+			// recovered panics always carry their stacktrace
+			if pe, ok := err.(*panicError); ok {
+				cause := causeOf(pe)
+				packet.Interfaces = append(packet.Interfaces, raven.NewException(cause, hook.processStacktrace(pe.stacktrace)))
+			}
 		}
 	}
 
+	// the transaction names the culprit better than the error
+	if transaction, ok := df.getTransaction(); ok {
+		packet.Culprit = transaction
+		packet.Interfaces = append(packet.Interfaces, transactionInterface(transaction))
+	}
+
+	dest := hook.destination(entry)
+	escaping := hook.escapingOf(dest)
+	if escaping != 0 {
+		escaping.escapePacket(packet)
+	}
+
 	// set other fields
-	dataExtra := hook.formatExtraData(df)
+	op := operationFromContext(entry.Context)
+	cmd, _ := df.getCmd()
+	dataExtra := hook.formatExtraData(df, escaping)
+	attachments = append(attachments, df.attachments...)
 	if packet.Extra == nil {
 		packet.Extra = dataExtra
 	} else {
@@ -261,56 +438,275 @@ func (hook *SentryHook) Fire(entry *logrus.Entry) error {
 			packet.Extra[k] = v
 		}
 	}
+	if op != nil {
+		setExtra(packet.Extra, extraOperation, op.name)
+		setExtra(packet.Extra, extraOperationDuration, op.elapsed(entry).String())
+	}
+	if info, ok := commandInfo(cmd, err); ok {
+		setExtra(packet.Extra, extraCommand, info)
+	}
+	if hook.goroutineInfo && entry.Level <= logrus.ErrorLevel {
+		if id, createdBy, ok := currentGoroutine(); ok {
+			setExtra(packet.Extra, extraGoroutineID, id)
+			if createdBy != "" {
+				setExtra(packet.Extra, extraGoroutineCreatedBy, createdBy)
+			}
+		}
+	}
+	if entry.Context != nil {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if info, ok := contextCancellation(entry.Context, now); ok {
+			setExtra(packet.Extra, extraContextCancellation, info)
+		}
+	}
+
+	if hook.strict {
+		if err := hook.checkStrict(df, packet); err != nil {
+			out.drop(DropStrict, err)
+			return err
+		}
+	}
+	if c := hook.crashLoop; c != nil && entry.Level <= logrus.FatalLevel {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		c.apply(packet, throttleKey(packet, entry), now)
+	}
+	if t := hook.throttle; t != nil {
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		allowed, suppressed := t.allow(throttleKey(packet, entry), now)
+		if !allowed {
+			hook.stats.update(func(s *Stats) { s.DroppedByThrottle++ })
+			out.drop(DropThrottle, nil)
+			return nil
+		}
+		if suppressed != 0 {
+			packet.Extra[extraSuppressedCount] = suppressed
+		}
+	}
+	if hook.configSnapshot {
+		if summary, ok := hook.takeConfigSnapshot(); ok {
+			packet.Extra[extraHookConfig] = summary
+		}
+	}
 
-	_, errCh := hook.client.Capture(packet, nil)
+	var parts []*raven.Packet
+	if s := hook.splitting; s != nil {
+		parts = s.split(packet)
+		if len(parts) != 0 {
+			packet = parts[0]
+		}
+	}
+	if hook.strict {
+		if err := checkStrictSize(packet, parts); err != nil {
+			out.drop(DropStrict, err)
+			hook.returnConfigSnapshot(packet, parts)
+			return err
+		}
+	}
+
+	client, release := hook.acquireDestination(entry, dest)
+	finalizers = batchFinalizers(client, packet, parts, finalizers)
+	out.fins = finalizers
+	ev := pendingEvent{
+		entry:       entry,
+		client:      client,
+		release:     release,
+		packet:      packet,
+		parts:       parts,
+		attachments: attachments,
+		finalizers:  finalizers,
+	}
+	if held, drop := hook.pause.hold(ev, &hook.stats); held {
+		if drop {
+			out.drop(DropPause, nil)
+			hook.returnConfigSnapshot(packet, parts)
+		}
+		return nil
+	}
+	if d := hook.queue; d != nil && hook.asynchronous {
+		if !hook.enqueue(d, ev) {
+			out.drop(DropQueue, nil)
+			hook.returnConfigSnapshot(packet, parts)
+		}
+		return nil
+	}
+	defer release()
+
+	asynchronous := hook.asynchronous
+	limit := hook.asyncLimit
+	if asynchronous && limit != nil {
+		acquired, sync := limit.acquire()
+		if !acquired {
+			if !sync {
+				hook.stats.update(func(s *Stats) { s.DroppedByOverflow++ })
+				out.drop(DropOverflow, nil)
+				hook.returnConfigSnapshot(packet, parts)
+				return nil
+			}
+			asynchronous = false
+			limit = nil
+		}
+	}
+
+	if len(attachments) != 0 {
+		hook.setAttachments(packet, attachments)
+	}
+	var eventID string
+	var errCh chan error
+	if len(parts) != 0 {
+		eventID, errCh = hook.captureParts(client, parts)
+	} else {
+		eventID, errCh = client.Capture(packet, nil)
+	}
+	if eventID == "" && len(attachments) != 0 {
+		hook.takeAttachments(packet)
+	}
+	excluded := eventID == "" && len(errCh) == 0
+	if excluded {
+		out.drop(hook.countExcluded(packet), nil)
+		hook.returnConfigSnapshot(packet, parts)
+	}
 
 	switch {
-	case hook.asynchronous:
+	case asynchronous && excluded:
+		// the client sampled out or excluded the event: no error will ever
+		// be received, so do not wait for it
+		if limit != nil {
+			limit.release()
+		}
+		return nil
+	case asynchronous:
 		// Our use of hook.mu guarantees that we are following the WaitGroup rule of
 		// not calling Add in parallel with Wait.
 		hook.wg.Add(1)
+		hook.stats.update(func(s *Stats) { s.Pending++ })
 		go func() {
-			if err := <-errCh; err != nil {
+			err := <-errCh
+			if err != nil {
 				for _, handlerFn := range hook.errorHandlers {
 					handlerFn(entry, err)
 				}
 			}
-			hook.wg.Done()
+			if limit != nil {
+				limit.release()
+			}
+			hook.stats.update(func(s *Stats) { s.Pending-- })
+			hook.finalizeAfter(finalizers, delivered(eventID, err), hook.wg.Done)
 		}()
 		return nil
 	case hook.Timeout == 0:
+		if !excluded {
+			hook.finalizeLater(finalizers, eventID, errCh)
+		}
 		return nil
 	default:
 		timeout := hook.Timeout
 		timeoutCh := time.After(timeout)
+		// stop waiting when the context of the entry is done, unless it
+		// already was when the entry was logged
+		var done <-chan struct{}
+		if entry.Context != nil && entry.Context.Err() == nil {
+			done = entry.Context.Done()
+		}
 		select {
 		case err := <-errCh:
 			for _, handlerFn := range hook.errorHandlers {
 				handlerFn(entry, err)
 			}
+			out.deliver(eventID, err)
 			return err
 		case <-timeoutCh:
+			if !excluded {
+				hook.finalizeLater(finalizers, eventID, errCh)
+			}
 			return fmt.Errorf("no response from sentry server in %s", timeout)
+		case <-done:
+			if !excluded {
+				hook.finalizeLater(finalizers, eventID, errCh)
+			}
+			return fmt.Errorf("no response from sentry server before the context was done: %v", entry.Context.Err())
 		}
 	}
 }
 
-// Flush waits for the log queue to empty. This function only does anything in
-// asynchronous mode.
-func (hook *SentryHook) Flush() {
-	if !hook.asynchronous {
-		return
+// severity returns the sentry severity of a logrus level.
+func (hook *SentryHook) severity(level logrus.Level) raven.Severity {
+	if severity, ok := hook.severityMap[level]; ok {
+		return severity
 	}
+	return severityMap[level]
+}
+
+// Flush waits for the log queue to empty in asynchronous mode and for the
+// events sent by ResumeSends, and sends the pending batches when batching is
+// enabled.
+func (hook *SentryHook) Flush() {
 	hook.mu.Lock() // Claim exclusive access; any logging goroutines will block until the flush completes
+	hook.wg.Wait()
+	hook.mu.Unlock()
+	hook.flushBatches()
+	hook.finalizing.wait()
+	if p := hook.throttleStore; p != nil {
+		_ = p.save(true)
+	}
+}
+
+// Close flushes the hook, then stops its background goroutines: the probes
+// of the regional DSNs, the health reports, the OnStats calls, the queue, the
+// spool replays and the saves of the throttle state. The hook should not be
+// used afterwards. The client of the hook is not closed.
+func (hook *SentryHook) Close() {
+	hook.Flush()
+
+	hook.mu.Lock()
 	defer hook.mu.Unlock()
+	if r := hook.regions; r != nil {
+		r.close()
+	}
+	hook.stopHealthReport()
+	hook.OnStats(0, nil)
+	_ = hook.SetQueue(nil, 0)
+	if s := hook.spool; s != nil {
+		s.close()
+	}
+	_ = hook.SetThrottleStore(nil)
+}
 
-	hook.wg.Wait()
+// captureStacktrace calls fn and returns its stacktrace. If fn panics or
+// returns invalid frames, it returns a nil stacktrace and the reason of the
+// failure, so the event can still be sent without it.
+// fn must not capture the current stack, as the wrapping would shift it.
+func captureStacktrace(fn func() *raven.Stacktrace) (stacktrace *raven.Stacktrace, failure string) {
+	defer func() {
+		if r := recover(); r != nil {
+			stacktrace, failure = nil, stacktraceFailurePanic
+		}
+	}()
+
+	stacktrace = fn()
+	if stacktrace == nil {
+		return nil, ""
+	}
+	for _, frame := range stacktrace.Frames {
+		if frame == nil || (frame.Filename == "" && frame.Function == "" && frame.Module == "") {
+			return nil, stacktraceFailureInvalidFrames
+		}
+	}
+	return stacktrace, ""
 }
 
 func (hook *SentryHook) findStacktrace(err error) *raven.Stacktrace {
 	var stacktrace *raven.Stacktrace
 	var stackErr errors.StackTrace
-	for err != nil {
+	for !isNil(err) {
 		// Find the earliest *raven.Stacktrace, or error.StackTrace
 		if tracer, ok := err.(Stacktracer); ok {
 			stacktrace = tracer.GetStacktrace()
@@ -340,6 +736,9 @@ func (hook *SentryHook) convertStackTrace(st errors.StackTrace) *raven.Stacktrac
 	for i := range stFrames {
 		pc := uintptr(stFrames[i])
 		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
 		file, line := fn.FileLine(pc)
 		frame := raven.NewStacktraceFrame(pc, fn.Name(), file, line, stConfig.Context, stConfig.InAppPrefixes)
 		if frame != nil {
@@ -347,6 +746,10 @@ func (hook *SentryHook) convertStackTrace(st errors.StackTrace) *raven.Stacktrac
 		}
 	}
 
+	if len(frames) == 0 {
+		return nil
+	}
+
 	// Sentry wants the frames with the oldest first, so reverse them
 	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
 		frames[i], frames[j] = frames[j], frames[i]
@@ -374,9 +777,10 @@ func (hook *SentryHook) AddErrorHandler(fn func(entry *logrus.Entry, err error))
 	hook.errorHandlers = append(hook.errorHandlers, fn)
 }
 
-func (hook *SentryHook) formatExtraData(df *dataField) (result map[string]interface{}) {
+func (hook *SentryHook) formatExtraData(df *dataField, escaping Escaping) (result map[string]interface{}) {
 	// create a map for passing to Sentry's extra data
 	result = make(map[string]interface{}, df.len())
+	var encodings map[string]string
 	for k, v := range df.data {
 		if df.isOmit(k) {
 			continue // skip already used special fields
@@ -385,18 +789,56 @@ func (hook *SentryHook) formatExtraData(df *dataField) (result map[string]interf
 			continue
 		}
 
+		// only the user values are escaped, not the values produced by
+		// the filters or the renderings of functions
+		escape := false
 		if fn, ok := hook.extraFilters[k]; ok {
 			v = fn(v) // apply custom filter
+		} else if hook.dropFuncFields && isFunc(v) {
+			continue
 		} else {
+			escape = escaping != 0 && !isFunc(v)
 			v = formatData(v) // use default formatter
 		}
+		v, encoding := hook.encodeLargeExtra(df, k, v)
+		if encoding != "" {
+			if encodings == nil {
+				encodings = make(map[string]string)
+			}
+			encodings[k+extraEncodingSuffix] = encoding
+			result[k] = v
+			continue
+		}
+		if s, ok := v.(string); ok && escape {
+			v = escaping.escape(s)
+		}
+		if limit := hook.extraSizeLimitOf(k); limit > 0 {
+			v = truncateExtra(v, limit)
+		}
 		result[k] = v
 	}
+	// once all the fields are set, as the encoded values are checked to not
+	// collide with them
+	for k, encoding := range encodings {
+		result[k] = encoding
+	}
 	return result
 }
 
+// setExtra sets the extra key to value, unless a field of the entry already
+// set it.
+func setExtra(extra map[string]interface{}, key string, value interface{}) {
+	if _, ok := extra[key]; !ok {
+		extra[key] = value
+	}
+}
+
 // formatData returns value as a suitable format.
 func formatData(value interface{}) (formatted interface{}) {
+	if t := reflect.TypeOf(value); t != nil && t.Kind() == reflect.Ptr && isNil(value) {
+		// the methods of a nil pointer may panic
+		return map[string]interface{}{"type": t.String(), "value": nil}
+	}
 	switch value := value.(type) {
 	case json.Marshaler:
 		return value
@@ -405,10 +847,38 @@ func formatData(value interface{}) (formatted interface{}) {
 	case fmt.Stringer:
 		return value.String()
 	default:
+		if isFunc(value) {
+			return formatFunc(value)
+		}
 		return value
 	}
 }
 
+// isFunc reports whether value is a function or a pointer to a function.
+func isFunc(value interface{}) bool {
+	t := reflect.TypeOf(value)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Func
+}
+
+// formatFunc returns the name of the function value as "func name(...)",
+// since functions can not be serialized.
+func formatFunc(value interface{}) string {
+	v := reflect.ValueOf(value)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.IsNil() {
+		return "func <nil>"
+	}
+	if fn := runtime.FuncForPC(v.Pointer()); fn != nil {
+		return "func " + fn.Name() + "(...)"
+	}
+	return "func <unknown>(...)"
+}
+
 // utility classes for breadcrumb support
 type Breadcrumbs struct {
 	Values []Value `json:"values"`
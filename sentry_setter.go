@@ -1,55 +1,169 @@
 package logrus_sentry
 
 import (
+	"regexp"
+	"strings"
+
 	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
 )
 
 // SetDefaultLoggerName sets default logger name tag.
 func (hook *SentryHook) SetDefaultLoggerName(name string) {
-	hook.client.SetDefaultLoggerName(name)
+	_ = hook.configureClients("logger_name", func(client *raven.Client) error {
+		client.SetDefaultLoggerName(name)
+		return nil
+	})
 }
 
 // SetEnvironment sets environment tag.
 func (hook *SentryHook) SetEnvironment(environment string) {
-	hook.client.SetEnvironment(environment)
+	_ = hook.configureClients("environment", func(client *raven.Client) error {
+		client.SetEnvironment(environment)
+		return nil
+	})
 }
 
 // SetHttpContext sets http client.
 func (hook *SentryHook) SetHttpContext(h *raven.Http) {
-	hook.client.SetHttpContext(h)
+	_ = hook.configureClients("http_context", func(client *raven.Client) error {
+		client.SetHttpContext(h)
+		return nil
+	})
 }
 
 // SetIgnoreErrors sets ignoreErrorsRegexp.
 func (hook *SentryHook) SetIgnoreErrors(errs ...string) error {
-	return hook.client.SetIgnoreErrors(errs)
+	if err := hook.configureClients("ignore_errors", func(client *raven.Client) error {
+		return client.SetIgnoreErrors(errs)
+	}); err != nil {
+		return err
+	}
+	// the clients compile the same expression, to tell the events they
+	// exclude from those they sample out
+	hook.ignoreErrors = regexp.MustCompile(strings.Join(errs, "|"))
+	return nil
 }
 
 // SetIncludePaths sets includePaths.
 func (hook *SentryHook) SetIncludePaths(p []string) {
-	hook.client.SetIncludePaths(p)
+	_ = hook.configureClients("include_paths", func(client *raven.Client) error {
+		client.SetIncludePaths(p)
+		return nil
+	})
 }
 
 // SetRelease sets release tag.
 func (hook *SentryHook) SetRelease(release string) {
-	hook.client.SetRelease(release)
+	_ = hook.configureClients("release", func(client *raven.Client) error {
+		client.SetRelease(release)
+		return nil
+	})
 }
 
 // SetSampleRate sets sampling rate.
 func (hook *SentryHook) SetSampleRate(rate float32) error {
-	return hook.client.SetSampleRate(rate)
+	if err := hook.configureClients("sample_rate", func(client *raven.Client) error {
+		return client.SetSampleRate(rate)
+	}); err != nil {
+		return err
+	}
+	hook.sampleRate = rate
+	hook.sampleRateSet = true
+	return nil
 }
 
 // SetTagsContext sets tags.
 func (hook *SentryHook) SetTagsContext(t map[string]string) {
-	hook.client.SetTagsContext(t)
+	_ = hook.configureClients("tags_context", func(client *raven.Client) error {
+		client.SetTagsContext(t)
+		return nil
+	})
 }
 
 // SetUserContext sets user.
 func (hook *SentryHook) SetUserContext(u *raven.User) {
-	hook.client.SetUserContext(u)
+	_ = hook.configureClients("user_context", func(client *raven.Client) error {
+		client.SetUserContext(u)
+		return nil
+	})
 }
 
 // SetServerName sets server_name tag.
 func (hook *SentryHook) SetServerName(serverName string) {
 	hook.serverName = serverName
 }
+
+// SetEventIDGenerator sets the function used to generate event_id when the
+// entry has no event_id field. The default generates a UUID4. If fn returns an
+// empty string, the raven client generates the ID instead. Passing nil
+// restores the default.
+func (hook *SentryHook) SetEventIDGenerator(fn func() string) {
+	if fn == nil {
+		fn = newEventID
+	}
+	hook.eventIDGenerator = fn
+}
+
+// SetUserFieldMapping sets the field names used to build the Sentry user
+// interface. Empty names fall back to user_id, user_name, user_email and
+// user_ip.
+func (hook *SentryHook) SetUserFieldMapping(m UserFieldMapping) {
+	hook.userFields = m
+}
+
+// SetStrict enables strict mode, intended for development. In strict mode,
+// Fire returns an error instead of sending a degraded event when a reserved
+// field has an unsupported value, or when the event exceeds the size limit
+// of the sentry server.
+func (hook *SentryHook) SetStrict(strict bool) {
+	hook.strict = strict
+}
+
+// SetLevelMapping overrides the sentry severity of logrus levels.
+// Levels missing from m keep their default severity.
+func (hook *SentryHook) SetLevelMapping(m map[logrus.Level]raven.Severity) {
+	severities := make(map[logrus.Level]raven.Severity, len(m))
+	for level, severity := range m {
+		severities[level] = severity
+	}
+	hook.severityMap = severities
+}
+
+// SetLevelTags sets tags added to every event of the given level.
+func (hook *SentryHook) SetLevelTags(level logrus.Level, tags map[string]string) {
+	if hook.levelTags == nil {
+		hook.levelTags = make(map[logrus.Level]map[string]string)
+	}
+	hook.levelTags[level] = tags
+}
+
+// SetLevelFingerprint sets the fingerprint of the events of the given level
+// which have no fingerprint field.
+func (hook *SentryHook) SetLevelFingerprint(level logrus.Level, fingerprint []string) {
+	if hook.levelFingerprints == nil {
+		hook.levelFingerprints = make(map[logrus.Level][]string)
+	}
+	hook.levelFingerprints[level] = fingerprint
+}
+
+// SetExtraSizeLimit sets the maximum serialized size in bytes of each extra
+// value. Larger values are truncated. Zero means no limit.
+func (hook *SentryHook) SetExtraSizeLimit(limit int) {
+	hook.extraSizeLimit = limit
+}
+
+// SetExtraKeySizeLimit overrides the maximum serialized size in bytes of the
+// extra value of key. Zero or a negative limit means no limit for key.
+func (hook *SentryHook) SetExtraKeySizeLimit(key string, limit int) {
+	if hook.extraKeyLimits == nil {
+		hook.extraKeyLimits = make(map[string]int)
+	}
+	hook.extraKeyLimits[key] = limit
+}
+
+// SetDropFuncFields drops the fields holding functions instead of sending
+// their names as extra data.
+func (hook *SentryHook) SetDropFuncFields(drop bool) {
+	hook.dropFuncFields = drop
+}
@@ -2,8 +2,10 @@ package logrus_sentry
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/musqdp/raven-go"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -175,3 +177,186 @@ func TestSetServerName(t *testing.T) {
 		a.Equal(server_name, packet.ServerName, "server name must be set")
 	})
 }
+
+func TestSetEventIDGenerator(t *testing.T) {
+	const (
+		generatedID = "00000000000000000000000000000001"
+		fieldID     = "11111111222233334444555555555555"
+	)
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		a.NotNil(parseUUID(packet.EventID), "default event_id must be a UUID")
+
+		hook.SetEventIDGenerator(func() string { return generatedID })
+		logger.Error(message)
+		packet = <-pch
+		a.Equal(generatedID, packet.EventID, "event_id must be generated by the custom generator")
+
+		logger.WithField("event_id", fieldID).Error(message)
+		packet = <-pch
+		a.Equal(fieldID, packet.EventID, "event_id field must take precedence")
+
+		hook.SetEventIDGenerator(nil)
+		logger.Error(message)
+		packet = <-pch
+		a.NotEqual(generatedID, packet.EventID, "nil must restore the default generator")
+		a.NotNil(parseUUID(packet.EventID), "default event_id must be a UUID")
+	})
+}
+
+func TestSetUserFieldMapping(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetUserFieldMapping(UserFieldMapping{ID: "account_id"})
+		logger.Hooks.Add(hook)
+
+		logger.WithFields(logrus.Fields{
+			"account_id": "A0001",
+			"user_email": "example@example.com",
+		}).Error(message)
+		packet := <-pch
+		a.Equal("A0001", packet.User.ID, "user id must be taken from the mapped field")
+		a.Equal("example@example.com", packet.User.Email, "user email must be taken from the default field")
+		a.NotContains(packet.Extra, "account_id", "mapped field must not be sent as extra")
+	})
+}
+
+func TestSetLevelMapping(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetLevelMapping(map[logrus.Level]raven.Severity{
+			logrus.WarnLevel: raven.INFO,
+		})
+		logger.Hooks.Add(hook)
+
+		logger.Warn(message)
+		packet := <-pch
+		a.Equal(raven.INFO, packet.Level, "mapped level must be used")
+
+		logger.Error(message)
+		packet = <-pch
+		a.Equal(raven.ERROR, packet.Level, "unmapped level must keep its default")
+	})
+}
+
+func TestSetLevelTagsAndFingerprint(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+			logrus.WarnLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetLevelTags(logrus.ErrorLevel, map[string]string{"alert": "true"})
+		hook.SetLevelFingerprint(logrus.ErrorLevel, []string{"errors"})
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: "alert", Value: "true"}, "level tags must be set")
+		a.Equal([]string{"errors"}, packet.Fingerprint, "level fingerprint must be set")
+
+		logger.WithField("fingerprint", []string{"custom"}).Error(message)
+		packet = <-pch
+		a.Equal([]string{"custom"}, packet.Fingerprint, "fingerprint field must take precedence")
+
+		logger.Warn(message)
+		packet = <-pch
+		a.NotContains(packet.Tags, raven.Tag{Key: "alert", Value: "true"}, "level tags must not be set on other levels")
+		a.Empty(packet.Fingerprint, "level fingerprint must not be set on other levels")
+	})
+}
+
+func TestSetExtraSizeLimit(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetExtraSizeLimit(20)
+		hook.SetExtraKeySizeLimit("response_body", 200)
+		hook.SetExtraKeySizeLimit("unlimited", 0)
+		logger.Hooks.Add(hook)
+
+		long := strings.Repeat("é", 50)
+		logger.WithFields(logrus.Fields{
+			"short":         "ok",
+			"long":          long,
+			"map":           map[string]string{"key": long},
+			"response_body": long,
+			"unlimited":     long,
+		}).Error(message)
+
+		packet := <-pch
+		a.Equal("ok", packet.Extra["short"])
+		a.Equal("éé...(truncated)", packet.Extra["long"], "UTF-8 sequences must not be split")
+		a.Equal(`{"k...(truncated)`, packet.Extra["map"], "other values must be truncated in their JSON form")
+		a.Equal(long, packet.Extra["response_body"], "key limit must take precedence")
+		a.Equal(long, packet.Extra["unlimited"])
+	})
+}
+
+func TestSetDropFuncFields(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		// logrus skips the func values of WithFields, but entries built
+		// directly or by the slog handler may carry them
+		fn := TestFormatFunc
+		entry := &logrus.Entry{
+			Logger:  logger,
+			Data:    logrus.Fields{"callback": TestSetDropFuncFields, "ptr": &fn, "other": "value"},
+			Level:   logrus.ErrorLevel,
+			Message: message,
+		}
+		a.NoError(hook.Fire(entry))
+		packet := <-pch
+		a.Equal("func github.com/musqdp/logrus_sentry.TestSetDropFuncFields(...)", packet.Extra["callback"])
+		a.Equal("func github.com/musqdp/logrus_sentry.TestFormatFunc(...)", packet.Extra["ptr"])
+
+		hook.SetDropFuncFields(true)
+		a.NoError(hook.Fire(entry))
+		packet = <-pch
+		a.NotContains(packet.Extra, "ptr")
+		a.NotContains(packet.Extra, "callback")
+		a.Equal("value", packet.Extra["other"])
+	})
+}
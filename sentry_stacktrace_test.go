@@ -120,3 +120,81 @@ func TestSentryStacktrace(t *testing.T) {
 		<-pch // check panic
 	})
 }
+
+type panicStacktracerError struct{}
+
+func (panicStacktracerError) Error() string { return "panicStacktracerError!" }
+
+func (panicStacktracerError) GetStacktrace() *raven.Stacktrace {
+	panic("cannot get stacktrace")
+}
+
+type invalidFramesStacktracerError struct {
+	frames []*raven.StacktraceFrame
+}
+
+func (invalidFramesStacktracerError) Error() string { return "invalidFramesStacktracerError!" }
+
+func (e invalidFramesStacktracerError) GetStacktrace() *raven.Stacktrace {
+	return &raven.Stacktrace{Frames: e.frames}
+}
+
+type bogusPkgStackTracerError struct{}
+
+func (bogusPkgStackTracerError) Error() string { return "bogusPkgStackTracerError!" }
+
+func (bogusPkgStackTracerError) StackTrace() pkgerrors.StackTrace {
+	return pkgerrors.StackTrace{pkgerrors.Frame(1)}
+}
+
+func TestSentryStacktraceFailure(t *testing.T) {
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		hook.StacktraceConfiguration.Enable = true
+		logger.Hooks.Add(hook)
+
+		tests := []struct {
+			err     error
+			failure string
+		}{
+			{panicStacktracerError{}, "panic"},
+			{pkgerrors.Wrap(panicStacktracerError{}, "wrapped"), "panic"},
+			{invalidFramesStacktracerError{[]*raven.StacktraceFrame{nil}}, "invalid_frames"},
+			{invalidFramesStacktracerError{[]*raven.StacktraceFrame{{}}}, "invalid_frames"},
+			{bogusPkgStackTracerError{}, ""},
+		}
+
+		for _, tt := range tests {
+			logger.WithError(tt.err).Error(message)
+			packet := <-pch
+
+			if packet.Message != message {
+				t.Errorf("message should have been %s, was %s", message, packet.Message)
+			}
+			if packet.Culprit != tt.err.Error() {
+				t.Errorf("culprit should have been %s, was %s", tt.err.Error(), packet.Culprit)
+			}
+			var failure string
+			for _, tag := range packet.Tags {
+				if tag.Key == "stacktrace_failure" {
+					failure = tag.Value
+				}
+			}
+			if failure != tt.failure {
+				t.Errorf("stacktrace_failure tag should have been %q, was %q", tt.failure, failure)
+			}
+			if tt.failure != "" && packet.Exception.Stacktrace != nil {
+				t.Error("Stacktrace should not be sent when it cannot be captured")
+			}
+			if tt.failure == "" && packet.Exception.Stacktrace == nil {
+				t.Error("Stacktrace should fall back to the current stack")
+			}
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -37,8 +39,12 @@ func getTestLogger() *logrus.Logger {
 // so need to explicitly construct one for purpose of test
 type resultPacket struct {
 	raven.Packet
-	Stacktrace raven.Stacktrace `json:"stacktrace"`
-	Exception  raven.Exception  `json:"exception"`
+	Stacktrace  raven.Stacktrace       `json:"stacktrace"`
+	Exception   raven.Exception        `json:"exception"`
+	User        raven.User             `json:"user"`
+	Dist        string                 `json:"dist"`
+	Contexts    map[string]interface{} `json:"contexts"`
+	Transaction string                 `json:"transaction"`
 }
 
 func WithTestDSN(t *testing.T, tf func(string, <-chan *resultPacket)) {
@@ -227,6 +233,28 @@ func TestSentryFingerprint(t *testing.T) {
 	})
 }
 
+func TestSentryTransaction(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		logger.WithFields(logrus.Fields{
+			"transaction": "GET /api/v1/users/:id",
+			"error":       errors.New("not found"),
+		}).Error(message)
+		packet := <-pch
+		a.Equal("GET /api/v1/users/:id", packet.Culprit, "transaction must take precedence over the error")
+		a.Equal("GET /api/v1/users/:id", packet.Transaction)
+		a.NotContains(packet.Extra, "transaction")
+	})
+}
+
 func TestAddIgnore(t *testing.T) {
 	hook := SentryHook{
 		ignoreFields: make(map[string]struct{}),
@@ -325,7 +353,7 @@ func TestFormatExtraData(t *testing.T) {
 			tt.key:          tt.value,
 		}
 		df := newDataField(fields)
-		result := hook.formatExtraData(df)
+		result := hook.formatExtraData(df, hook.escaping)
 
 		value, ok := result[tt.key]
 		if !tt.isExist {
@@ -363,6 +391,8 @@ func TestFormatData(t *testing.T) {
 		{"stringer_ptr", &myStringer{}, assertTypeString}, // implements .String()
 		{"not_stringer", notStringer{}, notStringer{}},
 		{"not_stringer_ptr", &notStringer{}, &notStringer{}},
+		{"func", TestFormatData, assertTypeString},
+		{"nil_func", (func())(nil), assertTypeString},
 	}
 
 	for _, tt := range tests {
@@ -378,6 +408,14 @@ func TestFormatData(t *testing.T) {
 	}
 }
 
+func TestFormatFunc(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("func github.com/musqdp/logrus_sentry.TestFormatFunc(...)", formatFunc(TestFormatFunc))
+	a.Equal("func <nil>", formatFunc((func())(nil)))
+	a.Contains(formatFunc(func() {}), "TestFormatFunc.func1(...)")
+}
+
 type myStringer struct{}
 
 func (myStringer) String() string { return "myStringer!" }
@@ -453,3 +491,131 @@ func httptestNewServer(handler func(http.ResponseWriter, *http.Request)) (server
 	)
 	return server, dsn
 }
+
+// nilError panics when its methods are called on a nil pointer.
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+func (e *nilError) Cause() error  { return nil }
+
+// wrapError has a typed nil cause.
+type wrapError struct{ cause *nilError }
+
+func (e wrapError) Error() string { return "wrapped" }
+func (e wrapError) Cause() error  { return e.cause }
+
+func TestNilValues(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		fields      logrus.Fields
+		culprit     string
+		extra       map[string]interface{}
+		description string
+	}{
+		{
+			logrus.Fields{"error": nil},
+			"",
+			map[string]interface{}{"error": nil},
+			"nil error",
+		},
+		{
+			logrus.Fields{"error": (*nilError)(nil)},
+			"",
+			map[string]interface{}{"error": map[string]interface{}{"type": "*logrus_sentry.nilError", "value": nil}},
+			"typed nil error",
+		},
+		{
+			logrus.Fields{"error": wrapError{}},
+			"wrapped",
+			map[string]interface{}{},
+			"typed nil cause",
+		},
+		{
+			logrus.Fields{"http_request": (*http.Request)(nil), "user": (*raven.User)(nil), "user_id": "A0001"},
+			"",
+			map[string]interface{}{
+				"http_request": map[string]interface{}{"type": "*http.Request", "value": nil},
+				"user":         map[string]interface{}{"type": "*raven.User", "value": nil},
+			},
+			"nil special fields",
+		},
+		{
+			logrus.Fields{"stringer": (*myStringer)(nil), "map": map[string]string(nil), "slice": []int(nil)},
+			"",
+			map[string]interface{}{
+				"stringer": map[string]interface{}{"type": "*logrus_sentry.myStringer", "value": nil},
+				"map":      nil,
+				"slice":    nil,
+			},
+			"nil extra values",
+		},
+	}
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		logger.Hooks.Add(hook)
+
+		for _, stacktrace := range []bool{false, true} {
+			hook.StacktraceConfiguration.Enable = stacktrace
+			for _, tt := range tests {
+				target := fmt.Sprintf("%s (stacktrace: %v)", tt.description, stacktrace)
+
+				a.NotPanics(func() {
+					logger.WithFields(tt.fields).Error(message)
+				}, target)
+				packet := <-pch
+				a.NotContains(packet.Culprit, "<nil>", target)
+				if !stacktrace {
+					a.Equal(tt.culprit, packet.Culprit, target)
+				}
+				for k, v := range tt.extra {
+					a.Equal(v, packet.Extra[k], "%s: %s", target, k)
+				}
+			}
+		}
+	})
+}
+
+func TestClose(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "close")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.NoError(hook.SetRegionalDSNs([]string{dsn}, time.Hour))
+	hook.EnableHealthReport(time.Hour)
+	hook.OnStats(time.Hour, func(Stats) {})
+	hook.SetThrottle(1, time.Hour)
+	a.NoError(hook.SetThrottleStore(NewFileThrottleStore(filepath.Join(dir, "throttle.json"))))
+	regions, health, store := hook.regions, hook.health, hook.throttleStore
+
+	hook.Close()
+	for name, stop := range map[string]chan struct{}{
+		"regional probes": regions.stop,
+		"health reports":  health.stop,
+		"throttle saves":  store.stop,
+	} {
+		select {
+		case <-stop:
+		default:
+			t.Errorf("the %s should be stopped", name)
+		}
+	}
+	a.Nil(hook.stats.stop, "the OnStats calls should be stopped")
+	a.NotPanics(hook.Close, "closing twice should not panic")
+}
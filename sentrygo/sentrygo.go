@@ -0,0 +1,334 @@
+// Package sentrygo converts raven packets to sentry-go events and back, so
+// that code written against one SDK's types (e.g. a before-send callback) can
+// keep working while the other one is used to send events.
+package sentrygo
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/musqdp/logrus_sentry"
+	"github.com/musqdp/raven-go"
+)
+
+// contextCulprit is the context holding the culprit of a packet, which
+// sentry-go events have no field for, in its culprit key. It is kept out of
+// the extra so that it cannot collide with the fields of the entries.
+const contextCulprit = "logrus_sentry"
+
+// ToSentryEvent converts a raven packet to a sentry-go event.
+//
+// The culprit of the packet is kept in the logrus_sentry context, as the
+// transaction of the event is set from the transaction interface only.
+//
+// Interfaces are converted from their JSON form, so custom interfaces are
+// supported as long as their class matches a sentry-go event field
+// (exception, stacktrace, request, user, breadcrumbs, dist, transaction,
+// contexts). Other object interfaces are added to the contexts under their
+// class.
+func ToSentryEvent(packet *raven.Packet) *sentry.Event {
+	if packet == nil {
+		return nil
+	}
+
+	event := &sentry.Event{
+		EventID:     sentry.EventID(packet.EventID),
+		Message:     packet.Message,
+		Timestamp:   time.Time(packet.Timestamp),
+		Level:       sentry.Level(packet.Level),
+		Logger:      packet.Logger,
+		Platform:    packet.Platform,
+		ServerName:  packet.ServerName,
+		Release:     packet.Release,
+		Environment: packet.Environment,
+		Modules:     packet.Modules,
+		Fingerprint: packet.Fingerprint,
+		Extra:       packet.Extra,
+	}
+	if len(packet.Tags) != 0 {
+		event.Tags = make(map[string]string, len(packet.Tags))
+		for _, tag := range packet.Tags {
+			event.Tags[tag.Key] = tag.Value
+		}
+	}
+
+	for _, in := range packet.Interfaces {
+		data, err := json.Marshal(in)
+		if err != nil {
+			continue
+		}
+		setEventInterface(event, in.Class(), data)
+	}
+	if packet.Culprit != "" {
+		context := sentry.Context{}
+		for k, v := range event.Contexts[contextCulprit] {
+			context[k] = v
+		}
+		context["culprit"] = packet.Culprit
+		setEventContext(event, contextCulprit, context)
+	}
+	return event
+}
+
+// setEventInterface sets the event field matching the class of an
+// interface from its JSON form.
+func setEventInterface(event *sentry.Event, class string, data []byte) {
+	switch class {
+	case "exception":
+		var chained struct {
+			Values []sentry.Exception `json:"values"`
+		}
+		if json.Unmarshal(data, &chained) == nil && chained.Values != nil {
+			event.Exception = append(event.Exception, chained.Values...)
+			return
+		}
+		var exception sentry.Exception
+		if json.Unmarshal(data, &exception) == nil {
+			event.Exception = append(event.Exception, exception)
+		}
+	case "stacktrace":
+		var stacktrace sentry.Stacktrace
+		if json.Unmarshal(data, &stacktrace) == nil {
+			event.Threads = append(event.Threads, sentry.Thread{
+				Stacktrace: &stacktrace,
+				Current:    true,
+			})
+		}
+	case "request":
+		var request struct {
+			sentry.Request
+			// raven allows either a string or an object
+			Data json.RawMessage `json:"data"`
+		}
+		if json.Unmarshal(data, &request) == nil {
+			req := request.Request
+			var s string
+			if json.Unmarshal(request.Data, &s) == nil {
+				req.Data = s
+			} else if len(request.Data) != 0 {
+				req.Data = string(request.Data)
+			}
+			event.Request = &req
+		}
+	case "user":
+		json.Unmarshal(data, &event.User)
+	case "breadcrumbs":
+		var crumbs logrus_sentry.Breadcrumbs
+		if json.Unmarshal(data, &crumbs) != nil {
+			return
+		}
+		for _, v := range crumbs.Values {
+			data, _ := v.Data.(map[string]interface{})
+			event.Breadcrumbs = append(event.Breadcrumbs, &sentry.Breadcrumb{
+				Type:      v.Type,
+				Category:  v.Category,
+				Message:   v.Message,
+				Data:      data,
+				Level:     sentry.Level(v.Level),
+				Timestamp: time.Unix(v.Timestamp, 0),
+			})
+		}
+	case "dist":
+		json.Unmarshal(data, &event.Dist)
+	case "transaction":
+		json.Unmarshal(data, &event.Transaction)
+	case "logentry":
+		var entry raven.Message
+		if json.Unmarshal(data, &entry) == nil && event.Message == "" {
+			event.Message = entry.Message
+		}
+	case "contexts":
+		var contexts map[string]sentry.Context
+		if json.Unmarshal(data, &contexts) != nil {
+			return
+		}
+		for name, context := range contexts {
+			setEventContext(event, name, context)
+		}
+	default:
+		var context sentry.Context
+		if json.Unmarshal(data, &context) == nil {
+			setEventContext(event, class, context)
+		}
+	}
+}
+
+func setEventContext(event *sentry.Event, name string, context sentry.Context) {
+	if event.Contexts == nil {
+		event.Contexts = make(map[string]sentry.Context)
+	}
+	event.Contexts[name] = context
+}
+
+// FromSentryEvent converts a sentry-go event to a raven packet.
+//
+// Exceptions, the current thread stacktrace, the request, the user and the
+// breadcrumbs are converted to the corresponding raven and logrus_sentry
+// types, so they can be inspected with type assertions. The culprit extra
+// set by ToSentryEvent is moved back to the culprit of the packet.
+func FromSentryEvent(event *sentry.Event) *raven.Packet {
+	if event == nil {
+		return nil
+	}
+
+	packet := &raven.Packet{
+		EventID:     string(event.EventID),
+		Message:     event.Message,
+		Timestamp:   raven.Timestamp(event.Timestamp),
+		Level:       raven.Severity(event.Level),
+		Logger:      event.Logger,
+		Platform:    event.Platform,
+		ServerName:  event.ServerName,
+		Release:     event.Release,
+		Environment: event.Environment,
+		Modules:     event.Modules,
+		Fingerprint: event.Fingerprint,
+		Extra:       event.Extra,
+	}
+	contexts := event.Contexts
+	if culprit, ok := contexts[contextCulprit]["culprit"].(string); ok {
+		packet.Culprit = culprit
+		contexts = make(map[string]sentry.Context, len(event.Contexts))
+		for name, context := range event.Contexts {
+			contexts[name] = context
+		}
+		delete(contexts, contextCulprit)
+		if len(event.Contexts[contextCulprit]) > 1 {
+			context := sentry.Context{}
+			for k, v := range event.Contexts[contextCulprit] {
+				if k != "culprit" {
+					context[k] = v
+				}
+			}
+			contexts[contextCulprit] = context
+		}
+	}
+	if len(event.Tags) != 0 {
+		keys := make([]string, 0, len(event.Tags))
+		for key := range event.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			packet.Tags = append(packet.Tags, raven.Tag{Key: key, Value: event.Tags[key]})
+		}
+	}
+
+	switch len(event.Exception) {
+	case 0:
+	case 1:
+		packet.Interfaces = append(packet.Interfaces, toRavenException(event.Exception[0]))
+	default:
+		exceptions := raven.Exceptions{}
+		for _, exception := range event.Exception {
+			exceptions.Values = append(exceptions.Values, toRavenException(exception))
+		}
+		packet.Interfaces = append(packet.Interfaces, exceptions)
+	}
+
+	for _, thread := range event.Threads {
+		if thread.Current && thread.Stacktrace != nil {
+			packet.Interfaces = append(packet.Interfaces, toRavenStacktrace(thread.Stacktrace))
+			break
+		}
+	}
+
+	if r := event.Request; r != nil {
+		h := &raven.Http{
+			URL:     r.URL,
+			Method:  r.Method,
+			Query:   r.QueryString,
+			Cookies: r.Cookies,
+			Headers: r.Headers,
+			Env:     r.Env,
+		}
+		if r.Data != "" {
+			h.Data = r.Data
+		}
+		packet.Interfaces = append(packet.Interfaces, h)
+	}
+
+	if !event.User.IsEmpty() {
+		packet.Interfaces = append(packet.Interfaces, &raven.User{
+			ID:       event.User.ID,
+			Username: event.User.Username,
+			Email:    event.User.Email,
+			IP:       event.User.IPAddress,
+		})
+	}
+
+	if len(event.Breadcrumbs) != 0 {
+		crumbs := &logrus_sentry.Breadcrumbs{}
+		for _, b := range event.Breadcrumbs {
+			v := logrus_sentry.Value{
+				Type:     b.Type,
+				Message:  b.Message,
+				Category: b.Category,
+				Level:    string(b.Level),
+			}
+			if !b.Timestamp.IsZero() {
+				v.Timestamp = b.Timestamp.Unix()
+			}
+			if b.Data != nil {
+				v.Data = b.Data
+			}
+			crumbs.Values = append(crumbs.Values, v)
+		}
+		packet.Interfaces = append(packet.Interfaces, crumbs)
+	}
+
+	if event.Dist != "" {
+		packet.Interfaces = append(packet.Interfaces, jsonInterface{"dist", event.Dist})
+	}
+	if event.Transaction != "" {
+		packet.Interfaces = append(packet.Interfaces, jsonInterface{"transaction", event.Transaction})
+	}
+	if len(contexts) != 0 {
+		packet.Interfaces = append(packet.Interfaces, jsonInterface{"contexts", contexts})
+	}
+	return packet
+}
+
+func toRavenException(exception sentry.Exception) *raven.Exception {
+	e := &raven.Exception{
+		Type:   exception.Type,
+		Value:  exception.Value,
+		Module: exception.Module,
+	}
+	if exception.Stacktrace != nil {
+		e.Stacktrace = toRavenStacktrace(exception.Stacktrace)
+	}
+	return e
+}
+
+func toRavenStacktrace(stacktrace *sentry.Stacktrace) *raven.Stacktrace {
+	s := &raven.Stacktrace{}
+	for _, f := range stacktrace.Frames {
+		s.Frames = append(s.Frames, &raven.StacktraceFrame{
+			Filename:     f.Filename,
+			Function:     f.Function,
+			Module:       f.Module,
+			Lineno:       f.Lineno,
+			Colno:        f.Colno,
+			AbsolutePath: f.AbsPath,
+			ContextLine:  f.ContextLine,
+			PreContext:   f.PreContext,
+			PostContext:  f.PostContext,
+			InApp:        f.InApp,
+		})
+	}
+	return s
+}
+
+// jsonInterface is a packet interface for the sentry-go event fields
+// raven.Packet has no field for.
+type jsonInterface struct {
+	class string
+	value interface{}
+}
+
+func (i jsonInterface) Class() string { return i.class }
+
+func (i jsonInterface) MarshalJSON() ([]byte, error) { return json.Marshal(i.value) }
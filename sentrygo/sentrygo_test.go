@@ -0,0 +1,148 @@
+package sentrygo
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/musqdp/logrus_sentry"
+	"github.com/musqdp/raven-go"
+	"github.com/stretchr/testify/assert"
+)
+
+type contexts map[string]interface{}
+
+func (contexts) Class() string { return "contexts" }
+
+func TestToSentryEvent(t *testing.T) {
+	a := assert.New(t)
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	packet := &raven.Packet{
+		Message:     "error message",
+		EventID:     "0123456789abcdef0123456789abcdef",
+		Timestamp:   raven.Timestamp(ts),
+		Level:       raven.ERROR,
+		Logger:      "logger",
+		Culprit:     "main.handler",
+		ServerName:  "host",
+		Release:     "v1.0.0",
+		Environment: "production",
+		Tags:        raven.Tags{{Key: "site", Value: "A"}},
+		Fingerprint: []string{"fp"},
+		Extra:       raven.Extra{"key": "value"},
+		Interfaces: []raven.Interface{
+			&raven.Exception{
+				Type:  "*errors.errorString",
+				Value: "failure",
+				Stacktrace: &raven.Stacktrace{Frames: []*raven.StacktraceFrame{
+					{Filename: "main.go", Function: "handler", Module: "main", Lineno: 42, InApp: true},
+				}},
+			},
+			&raven.Http{URL: "http://example.com/", Method: "POST", Data: map[string]string{"a": "b"}},
+			&raven.User{ID: "A0001", Email: "a@example.com"},
+			&logrus_sentry.Breadcrumbs{Values: []logrus_sentry.Value{
+				{Timestamp: ts.Unix(), Type: "error", Message: "crumb", Level: "error"},
+			}},
+			contexts{"trace": map[string]interface{}{"trace_id": "t"}},
+		},
+	}
+
+	event := ToSentryEvent(packet)
+	a.Equal(sentry.EventID(packet.EventID), event.EventID)
+	a.Equal("error message", event.Message)
+	a.True(ts.Equal(event.Timestamp))
+	a.Equal(sentry.LevelError, event.Level)
+	a.Empty(event.Transaction, "the culprit should not be the transaction")
+	a.Equal(map[string]string{"site": "A"}, event.Tags)
+	a.Equal([]string{"fp"}, event.Fingerprint)
+	a.Equal(map[string]interface{}{"key": "value"}, event.Extra)
+	a.Equal(raven.Extra{"key": "value"}, packet.Extra, "the packet should not be modified")
+	a.Equal("main.handler", FromSentryEvent(event).Culprit)
+
+	if a.Len(event.Exception, 1) {
+		a.Equal("failure", event.Exception[0].Value)
+		a.Equal([]sentry.Frame{
+			{Filename: "main.go", Function: "handler", Module: "main", Lineno: 42, InApp: true},
+		}, event.Exception[0].Stacktrace.Frames)
+	}
+	a.Equal(&sentry.Request{URL: "http://example.com/", Method: "POST", Data: `{"a":"b"}`}, event.Request)
+	a.Equal(sentry.User{ID: "A0001", Email: "a@example.com"}, event.User)
+	if a.Len(event.Breadcrumbs, 1) {
+		a.Equal("crumb", event.Breadcrumbs[0].Message)
+		a.Equal(sentry.LevelError, event.Breadcrumbs[0].Level)
+	}
+	a.Equal(map[string]sentry.Context{
+		"trace":         {"trace_id": "t"},
+		"logrus_sentry": {"culprit": "main.handler"},
+	}, event.Contexts)
+
+	a.Nil(ToSentryEvent(nil))
+}
+
+func TestFromSentryEvent(t *testing.T) {
+	a := assert.New(t)
+
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	event := &sentry.Event{
+		EventID:     "0123456789abcdef0123456789abcdef",
+		Message:     "error message",
+		Timestamp:   ts,
+		Level:       sentry.LevelWarning,
+		Transaction: "GET /users/:id",
+		Dist:        "42",
+		Extra:       map[string]interface{}{"culprit": "user value", "key": "value"},
+		Tags:        map[string]string{"b": "2", "a": "1"},
+		Contexts: map[string]sentry.Context{
+			"os":            {"name": "linux"},
+			"logrus_sentry": {"culprit": "main.handler"},
+		},
+		Exception: []sentry.Exception{
+			{Type: "cause", Value: "root cause"},
+			{Type: "wrapped", Value: "failure"},
+		},
+		Threads: []sentry.Thread{{Current: true, Stacktrace: &sentry.Stacktrace{
+			Frames: []sentry.Frame{{Function: "handler", Lineno: 42}},
+		}}},
+		User: sentry.User{ID: "A0001"},
+	}
+
+	packet := FromSentryEvent(event)
+	a.Equal("error message", packet.Message)
+	a.Equal(raven.WARNING, packet.Level)
+	a.Equal("main.handler", packet.Culprit)
+	a.Equal(raven.Extra{"culprit": "user value", "key": "value"}, packet.Extra, "the extra should be kept as is")
+	a.Equal(raven.Tags{{Key: "a", Value: "1"}, {Key: "b", Value: "2"}}, packet.Tags, "tags should be sorted")
+
+	classes := map[string]raven.Interface{}
+	for _, in := range packet.Interfaces {
+		classes[in.Class()] = in
+	}
+	if exceptions, ok := classes["exception"].(raven.Exceptions); a.True(ok, "chained exceptions should be converted") {
+		a.Len(exceptions.Values, 2)
+		a.Equal("failure", exceptions.Values[1].Value)
+	}
+	if stacktrace, ok := classes["stacktrace"].(*raven.Stacktrace); a.True(ok) {
+		a.Equal([]*raven.StacktraceFrame{{Function: "handler", Lineno: 42}}, stacktrace.Frames)
+	}
+	a.Equal(&raven.User{ID: "A0001"}, classes["user"])
+
+	b, err := packet.JSON()
+	a.NoError(err)
+	var body map[string]interface{}
+	a.NoError(json.Unmarshal(b, &body))
+	a.Equal("42", body["dist"])
+	a.Equal("GET /users/:id", body["transaction"])
+	a.Equal(map[string]interface{}{"os": map[string]interface{}{"name": "linux"}}, body["contexts"])
+
+	roundTrip := ToSentryEvent(packet)
+	a.Equal(event.Dist, roundTrip.Dist)
+	a.Equal(event.Transaction, roundTrip.Transaction)
+	a.Equal(event.Extra, roundTrip.Extra)
+	a.Equal(event.Contexts, roundTrip.Contexts)
+	a.Equal(event.Exception, roundTrip.Exception)
+	a.Equal(event.User, roundTrip.User)
+
+	a.Nil(FromSentryEvent(nil))
+}
@@ -0,0 +1,63 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// truncatedSuffix marks the extra values which were truncated.
+const truncatedSuffix = "...(truncated)"
+
+// extraSizeLimitOf returns the size limit of the extra value of key, or zero
+// if it has none.
+func (hook *SentryHook) extraSizeLimitOf(key string) int {
+	if limit, ok := hook.extraKeyLimits[key]; ok {
+		return limit
+	}
+	return hook.extraSizeLimit
+}
+
+// truncateExtra returns value, or its truncated JSON form if it is larger
+// than limit bytes. The truncated string is at most limit bytes once
+// serialized, quotes and escapes included.
+func truncateExtra(value interface{}, limit int) interface{} {
+	b, err := json.Marshal(value)
+	if err != nil || len(b) <= limit {
+		return value
+	}
+	s, ok := value.(string)
+	if !ok {
+		s = string(b)
+	}
+	return truncateEscaped(s, limit-len(`""`)-len(truncatedSuffix)) + truncatedSuffix
+}
+
+// truncateEscaped returns the longest prefix of s which is at most n bytes
+// once escaped in a JSON string, and does not split a UTF-8 sequence.
+func truncateEscaped(s string, n int) string {
+	size := 0
+	for i, r := range s {
+		size += escapedLen(r, s[i:])
+		if size > n {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// escapedLen returns the size of the rune r starting s once escaped by
+// encoding/json.
+func escapedLen(r rune, s string) int {
+	switch {
+	case r == '"' || r == '\\' || r == '\n' || r == '\r' || r == '\t':
+		return 2
+	case r < 0x20 || r == '<' || r == '>' || r == '&' || r == '\u2028' || r == '\u2029':
+		return len(`\u0000`)
+	case r == utf8.RuneError:
+		if _, size := utf8.DecodeRuneInString(s); size == 1 {
+			// invalid UTF-8 is replaced by \ufffd
+			return len(`\ufffd`)
+		}
+	}
+	return utf8.RuneLen(r)
+}
@@ -0,0 +1,28 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateExtra(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("<<<<"+truncatedSuffix, truncateExtra(strings.Repeat("<", 100), 40), "escapes must count in the limit")
+	for _, s := range []string{
+		strings.Repeat("<", 100),
+		strings.Repeat(`"\`, 50),
+		strings.Repeat("\x01\n", 50),
+		strings.Repeat("é&", 50),
+		strings.Repeat("\xff", 100),
+	} {
+		for _, limit := range []int{20, 33, 64} {
+			b, err := json.Marshal(truncateExtra(s, limit))
+			a.NoError(err)
+			a.True(len(b) <= limit, "%q truncated to %d bytes is %d bytes", s[:4], limit, len(b))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+//go:build go1.21
+// +build go1.21
+
+package logrus_sentry
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SlogHandler is a slog.Handler reporting records through a SentryHook, so
+// they go through the same pipeline as logrus entries: reserved fields,
+// extra, stacktraces, filters and routes.
+type SlogHandler struct {
+	hook   *SentryHook
+	attrs  logrus.Fields
+	prefix string
+}
+
+// SlogHandler returns a slog.Handler reporting the records whose level maps
+// to one of the hook's levels. Attributes keep their key, prefixed by their
+// groups separated by dots, so that reserved fields such as "user_id" or
+// "fingerprint" work as with logrus. It is typically combined with the
+// application's own handler:
+//
+//	logger := slog.New(hook.SlogHandler())
+func (hook *SentryHook) SlogHandler() *SlogHandler {
+	return &SlogHandler{hook: hook, attrs: logrus.Fields{}}
+}
+
+// slogLevel returns the logrus level of a slog level.
+func slogLevel(level slog.Level) logrus.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return logrus.DebugLevel
+	case level < slog.LevelWarn:
+		return logrus.InfoLevel
+	case level < slog.LevelError:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+// Enabled reports whether the level maps to one of the hook's levels.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.hook.hasLevel(slogLevel(level))
+}
+
+// Handle reports the record.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	data := make(logrus.Fields, len(h.attrs)+r.NumAttrs())
+	for k, v := range h.attrs {
+		data[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addSlogAttr(data, h.prefix, a)
+		return true
+	})
+
+	entry := &logrus.Entry{
+		Data:    data,
+		Time:    r.Time,
+		Level:   slogLevel(r.Level),
+		Message: r.Message,
+		Context: ctx,
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		entry.Caller = &frame
+	}
+	return h.hook.Fire(entry)
+}
+
+// WithAttrs returns a handler adding attrs to every record.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.clone()
+	for _, a := range attrs {
+		addSlogAttr(c.attrs, c.prefix, a)
+	}
+	return c
+}
+
+// WithGroup returns a handler prefixing the keys of the following
+// attributes with name.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := h.clone()
+	c.prefix += name + "."
+	return c
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	attrs := make(logrus.Fields, len(h.attrs))
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	return &SlogHandler{hook: h.hook, attrs: attrs, prefix: h.prefix}
+}
+
+// addSlogAttr adds the attribute to data, flattening groups.
+func addSlogAttr(data logrus.Fields, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			addSlogAttr(data, prefix, ga)
+		}
+		return
+	}
+	data[prefix+a.Key] = a.Value.Any()
+}
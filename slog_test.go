@@ -0,0 +1,38 @@
+//go:build go1.21
+// +build go1.21
+
+package logrus_sentry
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogHandler(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		handler := hook.SlogHandler()
+		a.False(handler.Enabled(context.Background(), slog.LevelWarn))
+		a.True(handler.Enabled(context.Background(), slog.LevelError))
+
+		logger := slog.New(handler).With("user_id", "A0001")
+		logger.WithGroup("request").Error(message, "id", 42, slog.Group("db", "table", "users"))
+
+		packet := <-pch
+		a.Equal(message, packet.Message)
+		a.Equal("error", string(packet.Level))
+		a.Equal("A0001", packet.User.ID, "reserved fields should be handled")
+		a.Equal(float64(42), packet.Extra["request.id"])
+		a.Equal("users", packet.Extra["request.db.table"])
+	})
+}
@@ -0,0 +1,119 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/musqdp/raven-go"
+)
+
+// extraHookConfig is the extra holding the configuration snapshot.
+const extraHookConfig = "hook_config"
+
+// SetConfigSnapshot attaches a summary of the configuration of the hook to
+// its first event, in the hook_config extra, so that the configuration which
+// produced the telemetry is visible in Sentry itself. The summary holds the
+// levels, sampling, names of the ignored and filtered fields, transport and
+// queue sizes, but no DSN, tag or field value.
+func (hook *SentryHook) SetConfigSnapshot(enabled bool) {
+	hook.configSnapshot = enabled
+}
+
+// takeConfigSnapshot returns the configuration snapshot the first time it is
+// called, and false afterwards unless the event carrying it was dropped.
+func (hook *SentryHook) takeConfigSnapshot() (map[string]interface{}, bool) {
+	if !atomic.CompareAndSwapUint32(&hook.configSnapshotSent, 0, 1) {
+		return nil, false
+	}
+	return hook.configSummary(), true
+}
+
+// returnConfigSnapshot makes the next event carry the configuration snapshot
+// again if the dropped packet, or one of its parts, carried it.
+func (hook *SentryHook) returnConfigSnapshot(packet *raven.Packet, parts []*raven.Packet) {
+	for _, p := range append([]*raven.Packet{packet}, parts...) {
+		if _, ok := p.Extra[extraHookConfig]; ok {
+			atomic.StoreUint32(&hook.configSnapshotSent, 0)
+			return
+		}
+	}
+}
+
+// configSummary returns a redacted summary of the configuration.
+func (hook *SentryHook) configSummary() map[string]interface{} {
+	levels := make([]string, len(hook.levels))
+	for i, level := range hook.levels {
+		levels[i] = level.String()
+	}
+	sampleRate := hook.sampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	}
+
+	summary := map[string]interface{}{
+		"levels":            levels,
+		"async":             hook.asynchronous,
+		"timeout":           hook.Timeout.String(),
+		"sample_rate":       sampleRate,
+		"strict":            hook.strict,
+		"stacktrace":        hook.StacktraceConfiguration.Enable,
+		"ignored_fields":    sortedKeys(hook.ignoreFields),
+		"filtered_fields":   sortedKeys(hook.extraFilters),
+		"fingerprint_rules": len(hook.fingerprintRules),
+		"thresholds":        len(hook.thresholds),
+		"routes":            len(hook.routes),
+		"transport":         describeTransport(hook.client.Transport),
+		"queue_size":        raven.MaxQueueBuffer,
+		"spool":             hook.spool != nil,
+	}
+	if hook.extraSizeLimit != 0 {
+		summary["extra_size_limit"] = hook.extraSizeLimit
+	}
+	if l := hook.asyncLimit; l != nil {
+		summary["async_limit"] = cap(l.slots)
+		summary["async_overflow"] = l.policy.String()
+	}
+	if d := hook.queue; d != nil {
+		summary["queue"] = fmt.Sprintf("%T", d.queue)
+		summary["queue_senders"] = d.senders
+	}
+	if t := hook.throttle; t != nil {
+		summary["throttle"] = fmt.Sprintf("%d/%s", t.rate, t.per)
+	}
+	if t := hook.tenants; t != nil {
+		summary["max_tenant_clients"] = t.max
+	}
+	if s := hook.splitting; s != nil {
+		summary["max_split_events"] = s.maxEvents
+	}
+	return summary
+}
+
+// describeTransport returns the types of the chain of transports, e.g.
+// "*logrus_sentry.statsTransport > *raven.HTTPTransport".
+func describeTransport(transport raven.Transport) string {
+	var types []string
+	for _, t := range transportChain(transport) {
+		types = append(types, fmt.Sprintf("%T", t))
+	}
+	return strings.Join(types, " > ")
+}
+
+// sortedKeys returns the sorted keys of m, a map with string keys.
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]struct{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]func(interface{}) interface{}:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
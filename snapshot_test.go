@@ -0,0 +1,82 @@
+package logrus_sentry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigSnapshot(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		a.NoError(hook.SetSampleRate(0.999))
+		hook.AddIgnore("request_id")
+		hook.AddExtraFilter("password", func(interface{}) interface{} { return scrubbedValue })
+		hook.SetConfigSnapshot(true)
+		logger.Hooks.Add(hook)
+
+		logger.Error(message)
+		packet := <-pch
+		summary, ok := packet.Extra[extraHookConfig].(map[string]interface{})
+		if a.True(ok, "the first event should have the hook configuration") {
+			a.Equal([]interface{}{"error"}, summary["levels"])
+			a.InDelta(0.999, summary["sample_rate"], 1e-6)
+			a.Equal([]interface{}{"request_id"}, summary["ignored_fields"])
+			a.Equal([]interface{}{"password"}, summary["filtered_fields"])
+			a.Contains(summary["transport"], "*raven.HTTPTransport")
+			a.EqualValues(100, summary["queue_size"])
+			for _, v := range summary {
+				if s, ok := v.(string); ok {
+					a.False(strings.Contains(s, dsn), "the DSN should not be sent")
+				}
+			}
+		}
+
+		logger.Error(message)
+		packet = <-pch
+		a.NotContains(packet.Extra, extraHookConfig, "only the first event should have the hook configuration")
+	})
+}
+
+func TestSetConfigSnapshotDropped(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		a.NoError(hook.SetIgnoreErrors("^ignored"))
+		hook.SetConfigSnapshot(true)
+		logger.Hooks.Add(hook)
+
+		logger.Error("ignored " + message)
+		hook.PauseSends()
+		hook.pause.events = make([]pendingEvent, maxPausedEvents)
+		logger.Error(message)
+		hook.pause.events = nil
+		hook.ResumeSends()
+
+		logger.Error(message)
+		packet := <-pch
+		a.Contains(packet.Extra, extraHookConfig, "the first event sent should have the hook configuration")
+	})
+}
+
+func TestDescribeTransport(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("", describeTransport(nil))
+	a.Equal("*logrus_sentry.statsTransport > *logrus_sentry.attachmentTransport",
+		describeTransport(&statsTransport{Transport: &attachmentTransport{}}))
+}
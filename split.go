@@ -0,0 +1,213 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	// tagSplitGroup is the tag shared by the events an entry was split into.
+	tagSplitGroup = "split_group"
+	// extraSplitPart is the extra holding the position of an event among
+	// the events an entry was split into, e.g. "2/3".
+	extraSplitPart = "split_part"
+	// extraSplitOmitted is the extra counting the extra values, or items
+	// of list values, omitted because of the cap on the number of events.
+	extraSplitOmitted = "split_omitted"
+
+	// splitOverhead is the room left in each part for the split tag and
+	// extras.
+	splitOverhead = 256
+)
+
+// eventSplitting configures the splitting of oversized events.
+type eventSplitting struct {
+	maxEvents int
+	size      int
+}
+
+// splitItem is an extra value sent whole in one of the parts, or an item of
+// a list value split among them.
+type splitItem struct {
+	key   string
+	value interface{}
+	size  int
+	list  bool
+}
+
+// EnableEventSplitting splits the events exceeding the size limit of the
+// sentry server, e.g. a batch job reporting hundreds of errors, into up to
+// maxEvents events instead of having them rejected. The extra values are
+// spread over the events, and the items of large list values are split
+// among them. The events share a split_group tag, and their position is sent
+// in the split_part extra. The values which do not fit in maxEvents events
+// are counted in the split_omitted extra of the last one.
+//
+// A maxEvents of zero disables the splitting.
+func (hook *SentryHook) EnableEventSplitting(maxEvents int) {
+	if maxEvents <= 0 {
+		hook.splitting = nil
+		return
+	}
+	hook.splitting = &eventSplitting{
+		maxEvents: maxEvents,
+		size:      maxPacketSize,
+	}
+}
+
+// split returns the parts of packet if it exceeds the size limit, and nil
+// otherwise or if its extra data can not be split.
+func (s *eventSplitting) split(packet *raven.Packet) []*raven.Packet {
+	b, err := packet.JSON()
+	if err != nil || len(b) <= s.size || len(packet.Extra) == 0 {
+		return nil
+	}
+	base := *packet
+	base.Extra = nil
+	if b, err = base.JSON(); err != nil {
+		return nil
+	}
+	budget := s.size - len(b) - splitOverhead
+	if budget <= 0 {
+		return nil
+	}
+
+	var parts []map[string]interface{}
+	var part map[string]interface{}
+	size, omitted := 0, 0
+	for _, item := range splitItems(packet.Extra, budget) {
+		items, hasList := part[item.key].([]interface{})
+		cost := item.size
+		if item.list && !hasList {
+			cost += len(strconv.Quote(item.key)) + 4
+		}
+		if part == nil || size+cost > budget {
+			if len(parts) == s.maxEvents {
+				omitted++
+				continue
+			}
+			part = make(map[string]interface{})
+			parts = append(parts, part)
+			size, items = 0, nil
+			if item.list {
+				cost = item.size + len(strconv.Quote(item.key)) + 4
+			}
+		}
+		if item.list {
+			part[item.key] = append(items, item.value)
+		} else {
+			part[item.key] = item.value
+		}
+		size += cost
+	}
+	if len(parts) < 2 {
+		return nil
+	}
+	if omitted != 0 {
+		parts[len(parts)-1][extraSplitOmitted] = omitted
+	}
+
+	group := newEventID()
+	packets := make([]*raven.Packet, len(parts))
+	for i, extra := range parts {
+		p := base
+		if i != 0 {
+			p.EventID = ""
+		}
+		// the client appends to the interfaces and tags of the packets
+		p.Interfaces = append([]raven.Interface(nil), packet.Interfaces...)
+		p.Tags = append(raven.Tags{{Key: tagSplitGroup, Value: group}}, packet.Tags...)
+		extra[extraSplitPart] = strconv.Itoa(i+1) + "/" + strconv.Itoa(len(parts))
+		p.Extra = extra
+		packets[i] = &p
+	}
+	return packets
+}
+
+// splitItems returns the extra values in key order, truncating the values
+// larger than budget, except for lists whose items are returned separately.
+// The list items come last, so that the cap on the number of parts omits
+// list items rather than whole values.
+func splitItems(extra map[string]interface{}, budget int) []splitItem {
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var items, listItems []splitItem
+	for _, k := range keys {
+		v := extra[k]
+		overhead := len(strconv.Quote(k)) + 2
+		if size := jsonSize(v) + overhead; size <= budget {
+			items = append(items, splitItem{key: k, value: v, size: size})
+			continue
+		}
+
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			v = truncateExtra(v, budget-overhead)
+			items = append(items, splitItem{key: k, value: v, size: jsonSize(v) + overhead})
+			continue
+		}
+		for i := 0; i < rv.Len(); i++ {
+			elem := rv.Index(i).Interface()
+			if jsonSize(elem) > budget-overhead-3 {
+				elem = truncateExtra(elem, budget-overhead-3)
+			}
+			listItems = append(listItems, splitItem{key: k, value: elem, size: jsonSize(elem) + 1, list: true})
+		}
+	}
+	return append(items, listItems...)
+}
+
+// jsonSize returns the size of the JSON form of v.
+func jsonSize(v interface{}) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+// captureParts captures the parts of a split event. It returns the event ID
+// of the first part captured, and a channel receiving the first error of
+// their sends once they are all sent.
+func (hook *SentryHook) captureParts(client *raven.Client, packets []*raven.Packet) (string, chan error) {
+	var eventID string
+	var chs []chan error
+	for i, p := range packets {
+		id, ch := client.Capture(p, nil)
+		if id == "" && len(ch) == 0 {
+			// sampled out or excluded: the attachments, kept with the
+			// first part, will not be sent
+			if i == 0 {
+				hook.takeAttachments(p)
+			}
+			continue
+		}
+		if eventID == "" {
+			eventID = id
+		}
+		chs = append(chs, ch)
+	}
+
+	errCh := make(chan error, 1)
+	if len(chs) == 0 {
+		return "", errCh
+	}
+	go func() {
+		var first error
+		for _, ch := range chs {
+			if err := <-ch; err != nil && first == nil {
+				first = err
+			}
+		}
+		errCh <- first
+	}()
+	return eventID, errCh
+}
@@ -0,0 +1,90 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnableEventSplitting(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewAsyncSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewAsyncSentryHook should be NoError")
+
+		hook.EnableEventSplitting(3)
+		hook.splitting.size = 8 << 10
+		logger.Hooks.Add(hook)
+
+		var errs []string
+		for i := 0; i < 300; i++ {
+			errs = append(errs, fmt.Sprintf("item %03d: %s", i, strings.Repeat("x", 90)))
+		}
+		logger.WithFields(logrus.Fields{
+			"errors": errs,
+			"job":    "import",
+		}).Error(message)
+
+		var group string
+		var received []interface{}
+		for i := 1; i <= 3; i++ {
+			packet := <-pch
+			a.Equal(fmt.Sprintf("%d/3", i), packet.Extra[extraSplitPart])
+			a.Equal(message, packet.Message)
+			for _, tag := range packet.Tags {
+				if tag.Key == tagSplitGroup {
+					if group == "" {
+						group = tag.Value
+					}
+					a.Equal(group, tag.Value, "parts must share their group")
+				}
+			}
+			items, _ := packet.Extra["errors"].([]interface{})
+			a.NotEmpty(items)
+			received = append(received, items...)
+			if i == 1 {
+				a.Equal("import", packet.Extra["job"], "whole values must come first")
+			}
+			if i == 3 {
+				a.Equal(float64(300-len(received)), packet.Extra[extraSplitOmitted])
+			}
+		}
+		a.NotEmpty(group, "parts must have a group tag")
+		a.Equal(errs[0], received[0], "items must keep their order")
+		hook.Flush()
+	})
+}
+
+func TestSplitPacket(t *testing.T) {
+	a := assert.New(t)
+
+	s := &eventSplitting{maxEvents: 3, size: maxPacketSize}
+	packet := raven.NewPacketWithExtra(message, raven.Extra{"key": "value"})
+	a.Nil(s.split(packet), "packets within the limit must not be split")
+
+	s.size = 1 << 10
+	packet = raven.NewPacketWithExtra(message, raven.Extra{"large": strings.Repeat("x", 2<<10)})
+	for _, part := range s.split(packet) {
+		if large, ok := part.Extra["large"].(string); ok {
+			a.True(strings.HasSuffix(large, truncatedSuffix), "single values must be truncated rather than split")
+			a.True(len(large) < 1<<10)
+		}
+	}
+
+	packet = raven.NewPacketWithExtra(message, raven.Extra{"list": strings.Split(strings.Repeat("item,", 2000), ",")})
+	parts := s.split(packet)
+	a.Len(parts, 3)
+	for _, part := range parts {
+		b, err := part.JSON()
+		a.NoError(err)
+		a.True(len(b) <= s.size, "parts must be within the size limit")
+	}
+}
@@ -0,0 +1,425 @@
+package logrus_sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	defaultSpoolMaxSize    = 10 << 20
+	defaultSpoolMinBackoff = time.Second
+	defaultSpoolMaxBackoff = 5 * time.Minute
+
+	spoolFileExt = ".json"
+)
+
+// SpoolConfig configures the on-disk spool of events which failed to be sent.
+type SpoolConfig struct {
+	// Dir is the directory holding the spooled events. It is created if missing.
+	Dir string
+	// MaxSize is the maximum total size in bytes of the spooled events.
+	// Events exceeding it are dropped. Defaults to 10MB.
+	MaxSize int64
+	// MinBackoff is the delay before retrying after a failure.
+	// It doubles on each consecutive failure. Defaults to 1 second.
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay between retries. Defaults to 5 minutes.
+	MaxBackoff time.Duration
+}
+
+// SpoolStats holds the counters of the spool.
+type SpoolStats struct {
+	// Spooled is the number of events written to the spool.
+	Spooled uint64
+	// Replayed is the number of spooled events successfully sent.
+	Replayed uint64
+	// Dropped is the number of events dropped because the spool was full
+	// or could not be written.
+	Dropped uint64
+}
+
+type spool struct {
+	// accessed atomically, kept first for 64-bit alignment
+	spooled  uint64
+	replayed uint64
+	dropped  uint64
+	seq      uint64
+
+	config SpoolConfig
+
+	mu         sync.Mutex // guards size, transports and httpClient
+	size       int64
+	transports map[string]raven.Transport
+	// httpClient replays the events whose client transport is unknown
+	httpClient *http.Client
+
+	replayMu sync.Mutex // serializes replays
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// spooledEvent is the on-disk format of a spooled event.
+// It contains the sentry auth header, so files are only readable by the owner.
+type spooledEvent struct {
+	URL        string          `json:"url"`
+	AuthHeader string          `json:"auth_header"`
+	Packet     json.RawMessage `json:"packet"`
+}
+
+// spoolTransport spools the packets its Transport fails to send.
+type spoolTransport struct {
+	raven.Transport
+
+	mu    sync.Mutex // guards spool, replaced by EnableSpool
+	spool *spool
+}
+
+func (t *spoolTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	t.mu.Lock()
+	s := t.spool
+	t.mu.Unlock()
+
+	s.mu.Lock()
+	s.transports[url] = t.Transport
+	s.mu.Unlock()
+
+	err := t.Transport.Send(url, authHeader, packet)
+	if err != nil && isRetryable(err) {
+		s.add(url, authHeader, packet)
+	}
+	return err
+}
+
+// EnableSpool enables the on-disk spool: events which fail to be sent are
+// written to cfg.Dir and retried in the background with exponential backoff.
+// Send errors are still reported to the error handlers. Calling it again
+// stops the retries of the previous spool and spools to cfg.Dir instead.
+func (hook *SentryHook) EnableSpool(cfg SpoolConfig) error {
+	if cfg.Dir == "" {
+		return errors.New("spool directory is empty")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = defaultSpoolMaxSize
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = defaultSpoolMinBackoff
+	}
+	if cfg.MaxBackoff < cfg.MinBackoff {
+		cfg.MaxBackoff = defaultSpoolMaxBackoff
+		if cfg.MaxBackoff < cfg.MinBackoff {
+			cfg.MaxBackoff = cfg.MinBackoff
+		}
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return err
+	}
+
+	s := &spool{
+		config:     cfg,
+		transports: make(map[string]raven.Transport),
+		httpClient: hook.httpClient,
+		stop:       make(chan struct{}),
+	}
+	files, err := s.files()
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		s.size += f.Size()
+	}
+
+	if hook.spool != nil {
+		hook.spool.close()
+	}
+	hook.spool = s
+	_ = hook.configureClients("spool", func(client *raven.Client) error {
+		hook.wrapSpoolTransport(client)
+		return nil
+	})
+	go s.run()
+	return nil
+}
+
+// wrapSpoolTransport makes the client spool its failed sends.
+func (hook *SentryHook) wrapSpoolTransport(client *raven.Client) {
+	if hook.spool == nil {
+		return
+	}
+	if t, ok := client.Transport.(*spoolTransport); ok {
+		t.mu.Lock()
+		t.spool = hook.spool
+		t.mu.Unlock()
+		return
+	}
+	client.Transport = &spoolTransport{
+		Transport: client.Transport,
+		spool:     hook.spool,
+	}
+}
+
+// SpoolStats returns the counters of the spool.
+func (hook *SentryHook) SpoolStats() SpoolStats {
+	if hook.spool == nil {
+		return SpoolStats{}
+	}
+	return hook.spool.stats()
+}
+
+// DrainSpool sends all spooled events now. It stops at the first failure
+// or when ctx is done, and returns the corresponding error.
+func (hook *SentryHook) DrainSpool(ctx context.Context) error {
+	if hook.spool == nil {
+		return nil
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ok, err := hook.spool.replayOne()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+}
+
+func (s *spool) stats() SpoolStats {
+	return SpoolStats{
+		Spooled:  atomic.LoadUint64(&s.spooled),
+		Replayed: atomic.LoadUint64(&s.replayed),
+		Dropped:  atomic.LoadUint64(&s.dropped),
+	}
+}
+
+func (s *spool) add(url, authHeader string, packet *raven.Packet) {
+	packetJSON, err := packet.JSON()
+	if err != nil {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	b, err := json.Marshal(spooledEvent{
+		URL:        url,
+		AuthHeader: authHeader,
+		Packet:     packetJSON,
+	})
+	if err != nil {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+int64(len(b)) > s.config.MaxSize {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), atomic.AddUint64(&s.seq, 1), spoolFileExt)
+	if err := ioutil.WriteFile(filepath.Join(s.config.Dir, name), b, 0600); err != nil {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.size += int64(len(b))
+	atomic.AddUint64(&s.spooled, 1)
+}
+
+// files returns the spooled files, oldest first.
+func (s *spool) files() ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(s.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	files := infos[:0]
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), spoolFileExt) {
+			files = append(files, info)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+	return files, nil
+}
+
+// replayOne sends the oldest spooled event. It returns false if the spool
+// is empty.
+func (s *spool) replayOne() (bool, error) {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	files, err := s.files()
+	if err != nil {
+		return false, err
+	}
+	if len(files) == 0 {
+		return false, nil
+	}
+
+	f := files[0]
+	path := filepath.Join(s.config.Dir, f.Name())
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	var ev spooledEvent
+	packet, err := decodeSpooledEvent(b, &ev)
+	if err == nil {
+		if err = s.transport(ev.URL).Send(ev.URL, ev.AuthHeader, packet); err != nil && isRetryable(err) {
+			return false, err
+		}
+		if err == nil {
+			atomic.AddUint64(&s.replayed, 1)
+		} else {
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	} else {
+		// undecodable files can never be sent
+		atomic.AddUint64(&s.dropped, 1)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	s.size -= f.Size()
+	s.mu.Unlock()
+	return true, nil
+}
+
+// transport returns the transport of the client sending to url, or a
+// transport using the HTTP client of the hook for the events spooled by a
+// previous process.
+func (s *spool) transport(url string) raven.Transport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.transports[url]; ok {
+		return t
+	}
+	httpClient := s.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &raven.HTTPTransport{Client: httpClient}
+}
+
+// run replays the spooled events in the background until the spool is
+// closed.
+func (s *spool) run() {
+	backoff := s.config.MinBackoff
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-s.stop:
+			return
+		}
+		for {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			ok, err := s.replayOne()
+			if err != nil {
+				backoff *= 2
+				if backoff > s.config.MaxBackoff {
+					backoff = s.config.MaxBackoff
+				}
+				break
+			}
+			backoff = s.config.MinBackoff
+			if !ok {
+				break
+			}
+		}
+		timer.Reset(backoff)
+	}
+}
+
+// close stops the replays in the background. The spooled events are kept.
+func (s *spool) close() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// decodeSpooledEvent rebuilds the packet of a spooled event. Interfaces
+// (exception, request, user...) are kept as raw JSON.
+func decodeSpooledEvent(b []byte, ev *spooledEvent) (*raven.Packet, error) {
+	if err := json.Unmarshal(b, ev); err != nil {
+		return nil, err
+	}
+	packet := &raven.Packet{}
+	if err := json.Unmarshal(ev.Packet, packet); err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(ev.Packet, &fields); err != nil {
+		return nil, err
+	}
+	for class, data := range fields {
+		if _, ok := packetJSONFields[class]; ok {
+			continue
+		}
+		packet.Interfaces = append(packet.Interfaces, rawInterface{class: class, data: data})
+	}
+	return packet, nil
+}
+
+// packetJSONFields are the keys of raven.Packet which are not interfaces.
+var packetJSONFields = map[string]struct{}{
+	"message":     {},
+	"event_id":    {},
+	"project":     {},
+	"timestamp":   {},
+	"level":       {},
+	"logger":      {},
+	"platform":    {},
+	"culprit":     {},
+	"server_name": {},
+	"release":     {},
+	"environment": {},
+	"tags":        {},
+	"modules":     {},
+	"fingerprint": {},
+	"extra":       {},
+}
+
+// rawInterface is a packet interface restored from its JSON form.
+type rawInterface struct {
+	class string
+	data  json.RawMessage
+}
+
+func (r rawInterface) Class() string { return r.class }
+
+func (r rawInterface) MarshalJSON() ([]byte, error) { return r.data, nil }
+
+// isRetryable reports whether a failed send may succeed later.
+func isRetryable(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+	var status int
+	if _, scanErr := fmt.Sscanf(err.Error(), "raven: got http status %d", &status); scanErr == nil {
+		return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+	}
+	return false
+}
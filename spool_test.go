@@ -0,0 +1,212 @@
+package logrus_sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpool(t *testing.T) {
+	a := assert.New(t)
+
+	var failing int32 = 1
+	received := make(chan map[string]interface{}, 1)
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		if atomic.LoadInt32(&failing) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		received <- body
+	})
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "logrus_sentry_spool")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = time.Second
+	a.NoError(hook.EnableSpool(SpoolConfig{
+		Dir:        dir,
+		MinBackoff: time.Hour, // only replay through DrainSpool
+	}))
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.WithField("user_id", "A0001").Error(message)
+
+	files, _ := ioutil.ReadDir(dir)
+	a.Len(files, 1, "failed event should be spooled")
+	a.Equal(SpoolStats{Spooled: 1}, hook.SpoolStats())
+
+	ctx := context.Background()
+	a.Error(hook.DrainSpool(ctx), "DrainSpool should fail while the server is down")
+
+	atomic.StoreInt32(&failing, 0)
+	a.NoError(hook.DrainSpool(ctx))
+	body := <-received
+	a.Equal(message, body["message"])
+	a.Equal(map[string]interface{}{"id": "A0001"}, body["user"], "interfaces should be replayed")
+
+	files, _ = ioutil.ReadDir(dir)
+	a.Len(files, 0, "replayed event should be removed")
+	a.Equal(SpoolStats{Spooled: 1, Replayed: 1}, hook.SpoolStats())
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	a.Equal(context.Canceled, hook.DrainSpool(cancelled))
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	})
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "logrus_sentry_spool")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = time.Second
+	a.NoError(hook.EnableSpool(SpoolConfig{
+		Dir:        dir,
+		MaxSize:    10,
+		MinBackoff: time.Hour,
+	}))
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+
+	a.Equal(SpoolStats{Dropped: 1}, hook.SpoolStats(), "event exceeding MaxSize should be dropped")
+}
+
+func TestEnableSpoolTwice(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logrus_sentry_spool")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	hook, err := NewSentryHook("", []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.NoError(hook.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}))
+	first := hook.spool
+	a.NoError(hook.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}))
+
+	select {
+	case <-first.stop:
+	default:
+		t.Error("the previous spool should be stopped")
+	}
+	if transport, ok := hook.client.Transport.(*spoolTransport); a.True(ok) {
+		a.True(transport.spool == hook.spool, "the transport should use the new spool")
+	}
+	hook.spool.close()
+}
+
+func TestEnableSpoolWhileSending(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logrus_sentry_spool")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		a.NoError(hook.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}))
+
+		logger := getTestLogger()
+		logger.Hooks.Add(hook)
+
+		const n = 10
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < n; i++ {
+				logger.Error(message)
+			}
+		}()
+		for i := 0; i < n; i++ {
+			a.NoError(hook.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}))
+			<-pch
+		}
+		<-done
+		hook.spool.close()
+	})
+}
+
+func TestIsRetryable(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("raven: got http status 503 - x-sentry-error: "), true},
+		{fmt.Errorf("raven: got http status 429 - x-sentry-error: "), true},
+		{fmt.Errorf("raven: got http status 400 - x-sentry-error: invalid"), false},
+		{fmt.Errorf("error serializing packet: bad"), false},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, isRetryable(tt.err), tt.err.Error())
+	}
+}
+
+func TestSpoolHTTPClient(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "logrus_sentry_spool")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	httpClient := &http.Client{Timeout: time.Second}
+	hook, err := NewSentryHook("", []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.NoError(hook.EnableSpool(SpoolConfig{Dir: dir, MinBackoff: time.Hour}))
+	defer hook.spool.close()
+
+	transport, ok := hook.spool.transport("http://sentry.invalid/api/1/store/").(*raven.HTTPTransport)
+	if a.True(ok) {
+		a.True(transport.Client == http.DefaultClient)
+	}
+	hook.SetHTTPClient(httpClient)
+	transport, ok = hook.spool.transport("http://sentry.invalid/api/1/store/").(*raven.HTTPTransport)
+	if a.True(ok) {
+		a.True(transport.Client == httpClient, "replays should use the HTTP client of the hook")
+	}
+}
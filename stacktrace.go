@@ -0,0 +1,76 @@
+package logrus_sentry
+
+import (
+	"strings"
+
+	"github.com/musqdp/raven-go"
+)
+
+// moduleCacheDir is the path segment of the go module cache.
+const moduleCacheDir = "/pkg/mod/"
+
+// SetInAppPrefixes sets the package prefixes whose stack frames are marked
+// as in_app, e.g. []string{"github.com/mycorp/"}.
+func (hook *SentryHook) SetInAppPrefixes(prefixes []string) {
+	hook.StacktraceConfiguration.InAppPrefixes = prefixes
+}
+
+// processStacktrace skips and trims the frames of st according to the
+// stacktrace configuration. The frames of st are left untouched.
+func (hook *SentryHook) processStacktrace(st *raven.Stacktrace) *raven.Stacktrace {
+	stConfig := &hook.StacktraceConfiguration
+	if st == nil || !(stConfig.SkipRuntimeFrames || stConfig.SkipVendorFrames || stConfig.TrimModulePaths) {
+		return st
+	}
+
+	frames := make([]*raven.StacktraceFrame, 0, len(st.Frames))
+	for _, frame := range st.Frames {
+		if stConfig.SkipRuntimeFrames && isRuntimeFrame(frame) {
+			continue
+		}
+		if stConfig.SkipVendorFrames && isVendorFrame(frame) {
+			continue
+		}
+		f := *frame
+		if stConfig.TrimModulePaths {
+			f.Filename = trimModulePath(f.Filename)
+		}
+		frames = append(frames, &f)
+	}
+	if len(frames) == 0 {
+		return nil
+	}
+	return &raven.Stacktrace{Frames: frames}
+}
+
+func isRuntimeFrame(frame *raven.StacktraceFrame) bool {
+	return frame.Module == "runtime" || strings.HasPrefix(frame.Module, "runtime/")
+}
+
+func isVendorFrame(frame *raven.StacktraceFrame) bool {
+	return strings.HasPrefix(frame.Module, "vendor/") ||
+		strings.Contains(frame.Module, "/vendor/") ||
+		strings.Contains(frame.Filename, "/vendor/")
+}
+
+// trimModulePath turns a file of the module cache, or of a -trimpath build,
+// into its import path, e.g.
+// "/home/u/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go" into
+// "github.com/pkg/errors/errors.go".
+func trimModulePath(filename string) string {
+	if i := strings.LastIndex(filename, moduleCacheDir); i != -1 {
+		filename = filename[i+len(moduleCacheDir):]
+	} else if strings.HasPrefix(filename, "/") {
+		return filename
+	}
+
+	at := strings.Index(filename, "@")
+	if at == -1 {
+		return filename
+	}
+	end := strings.Index(filename[at:], "/")
+	if end == -1 {
+		return filename
+	}
+	return filename[:at] + filename[at+end:]
+}
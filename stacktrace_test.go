@@ -0,0 +1,59 @@
+package logrus_sentry
+
+import (
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrimModulePath(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"/home/u/go/pkg/mod/github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors/errors.go"},
+		{"/home/u/go/pkg/mod/github.com/sirupsen/logrus@v1.4.2/hooks/test/test.go", "github.com/sirupsen/logrus/hooks/test/test.go"},
+		{"github.com/pkg/errors@v0.9.1/errors.go", "github.com/pkg/errors/errors.go"},
+		{"github.com/mycorp/app/main.go", "github.com/mycorp/app/main.go"},
+		{"/src/app/user@example/main.go", "/src/app/user@example/main.go"},
+		{"/usr/local/go/src/net/http/server.go", "/usr/local/go/src/net/http/server.go"},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, trimModulePath(tt.filename), tt.filename)
+	}
+}
+
+func TestProcessStacktrace(t *testing.T) {
+	a := assert.New(t)
+
+	st := &raven.Stacktrace{Frames: []*raven.StacktraceFrame{
+		{Module: "runtime", Function: "main", Filename: "runtime/proc.go"},
+		{Module: "github.com/mycorp/app/vendor/github.com/lib/pq", Function: "Query", Filename: "github.com/mycorp/app/vendor/github.com/lib/pq/conn.go"},
+		{Module: "github.com/mycorp/app", Function: "handle", Filename: "/home/u/go/pkg/mod/github.com/mycorp/app@v1.0.0/handler.go"},
+	}}
+
+	hook := SentryHook{}
+	a.Equal(st, hook.processStacktrace(st), "stacktrace must be untouched by default")
+
+	hook.StacktraceConfiguration.SkipRuntimeFrames = true
+	hook.StacktraceConfiguration.SkipVendorFrames = true
+	hook.StacktraceConfiguration.TrimModulePaths = true
+	processed := hook.processStacktrace(st)
+	a.Len(processed.Frames, 1, "runtime and vendored frames must be skipped")
+	a.Equal("github.com/mycorp/app/handler.go", processed.Frames[0].Filename, "module path must be trimmed")
+	a.Equal("/home/u/go/pkg/mod/github.com/mycorp/app@v1.0.0/handler.go", st.Frames[2].Filename, "original frames must be untouched")
+
+	a.Nil(hook.processStacktrace(&raven.Stacktrace{Frames: st.Frames[:2]}), "stacktrace without frames must be nil")
+}
+
+func TestSetInAppPrefixes(t *testing.T) {
+	a := assert.New(t)
+
+	hook := SentryHook{}
+	hook.SetInAppPrefixes([]string{"github.com/mycorp/"})
+	a.Equal([]string{"github.com/mycorp/"}, hook.StacktraceConfiguration.InAppPrefixes)
+}
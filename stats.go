@@ -0,0 +1,160 @@
+package logrus_sentry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+// Stats holds the counters and health information of the hook.
+type Stats struct {
+	// Sent is the number of events successfully sent.
+	Sent uint64
+	// Failed is the number of events which failed to be sent.
+	Failed uint64
+	// DroppedByThreshold is the number of events dropped because their
+	// call site was below its threshold.
+	DroppedByThreshold uint64
+	// DroppedByQueue is the number of events dropped because the send queue
+	// of the client was full.
+	DroppedByQueue uint64
+	// DroppedByOverflow is the number of events dropped because all the
+	// senders of an asynchronous hook were busy.
+	DroppedByOverflow uint64
+	// DroppedByThrottle is the number of events dropped because their
+	// fingerprint exceeded the throttle rate.
+	DroppedByThrottle uint64
+	// DroppedByPause is the number of events dropped because the buffer of
+	// the paused sends was full.
+	DroppedByPause uint64
+	// DroppedBySampling is the number of events sampled out by the client.
+	DroppedBySampling uint64
+	// DroppedByIgnoreRules is the number of events whose message matched
+	// the expressions of SetIgnoreErrors.
+	DroppedByIgnoreRules uint64
+	// Pending is the number of events of an asynchronous hook waiting to
+	// be sent.
+	Pending int64
+	// LastSendLatency is the duration of the last send.
+	LastSendLatency time.Duration
+	// LastError is the error of the last failed send, and LastErrorTime
+	// its time.
+	LastError     error
+	LastErrorTime time.Time
+}
+
+type hookStats struct {
+	mu    sync.Mutex
+	stats Stats
+	stop  chan struct{}
+}
+
+// statsTransport records the sends of its Transport in the hook stats.
+type statsTransport struct {
+	raven.Transport
+	stats *hookStats
+}
+
+func (t *statsTransport) Send(url, authHeader string, packet *raven.Packet) error {
+	if url == "" {
+		return t.Transport.Send(url, authHeader, packet)
+	}
+	start := time.Now()
+	err := t.Transport.Send(url, authHeader, packet)
+	t.stats.recordSend(time.Since(start), err)
+	return err
+}
+
+// instrumentClient makes the client record its sends and drops in the hook
+// stats, and send the attachments of the events.
+func (hook *SentryHook) instrumentClient(client *raven.Client) {
+	if client == nil {
+		return
+	}
+	if _, ok := client.Transport.(*statsTransport); ok {
+		return
+	}
+	client.Transport = &statsTransport{
+		Transport: &attachmentTransport{
+			Transport: client.Transport,
+			hook:      hook,
+		},
+		stats: &hook.stats,
+	}
+	dropHandler := client.DropHandler
+	client.DropHandler = func(packet *raven.Packet) {
+		hook.takeAttachments(packet)
+		hook.returnConfigSnapshot(packet, nil)
+		hook.stats.update(func(s *Stats) { s.DroppedByQueue++ })
+		if dropHandler != nil {
+			dropHandler(packet)
+		}
+	}
+}
+
+// Stats returns the counters and health information of the hook.
+func (hook *SentryHook) Stats() Stats {
+	hook.stats.mu.Lock()
+	defer hook.stats.mu.Unlock()
+	return hook.stats.stats
+}
+
+// OnStats calls fn with the stats of the hook every interval, until OnStats
+// is called again. A nil fn stops the calls.
+func (hook *SentryHook) OnStats(interval time.Duration, fn func(Stats)) {
+	hook.stats.mu.Lock()
+	defer hook.stats.mu.Unlock()
+	if hook.stats.stop != nil {
+		close(hook.stats.stop)
+		hook.stats.stop = nil
+	}
+	if fn == nil || interval <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	hook.stats.stop = stop
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				fn(hook.Stats())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// countExcluded counts an event the client did not send, because it sampled
+// it out or its message matched the ignore rules, and returns the reason.
+func (hook *SentryHook) countExcluded(packet *raven.Packet) string {
+	if r := hook.ignoreErrors; r != nil && r.MatchString(packet.Message) {
+		hook.stats.update(func(s *Stats) { s.DroppedByIgnoreRules++ })
+		return DropIgnoreRules
+	}
+	hook.stats.update(func(s *Stats) { s.DroppedBySampling++ })
+	return DropSampling
+}
+
+func (s *hookStats) update(fn func(*Stats)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.stats)
+}
+
+func (s *hookStats) recordSend(latency time.Duration, err error) {
+	s.update(func(stats *Stats) {
+		stats.LastSendLatency = latency
+		if err != nil {
+			stats.Failed++
+			stats.LastError = err
+			stats.LastErrorTime = time.Now()
+		} else {
+			stats.Sent++
+		}
+	})
+}
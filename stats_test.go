@@ -0,0 +1,66 @@
+package logrus_sentry
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	a := assert.New(t)
+
+	var failing int32
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		if atomic.LoadInt32(&failing) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		}
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = time.Second
+	hook.AddCallSiteThreshold("stats_test.go", 2, time.Minute)
+
+	logger := getTestLogger()
+	logger.SetReportCaller(true)
+	logger.Hooks.Add(hook)
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			atomic.StoreInt32(&failing, 1)
+		}
+		logger.Error(message)
+	}
+
+	stats := hook.Stats()
+	a.Equal(uint64(1), stats.Sent)
+	a.Equal(uint64(1), stats.Failed)
+	a.Equal(uint64(1), stats.DroppedByThreshold)
+	a.True(stats.LastSendLatency > 0)
+	if a.Error(stats.LastError) {
+		a.Contains(stats.LastError.Error(), "503")
+	}
+	a.False(stats.LastErrorTime.IsZero())
+
+	statsCh := make(chan Stats, 1)
+	hook.OnStats(10*time.Millisecond, func(s Stats) {
+		select {
+		case statsCh <- s:
+		default:
+		}
+	})
+	select {
+	case s := <-statsCh:
+		a.Equal(stats, s)
+	case <-time.After(time.Second):
+		t.Error("OnStats callback should be called periodically")
+	}
+	hook.OnStats(0, nil)
+}
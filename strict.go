@@ -0,0 +1,39 @@
+package logrus_sentry
+
+import (
+	"fmt"
+
+	"github.com/musqdp/raven-go"
+)
+
+// maxPacketSize is the maximum size of an uncompressed event accepted by
+// the sentry server.
+const maxPacketSize = 1 << 20
+
+// checkStrict returns an error if the packet would be degraded because of a
+// misconfiguration.
+func (hook *SentryHook) checkStrict(df *dataField, packet *raven.Packet) error {
+	if keys := df.unusedReservedFields(); len(keys) != 0 {
+		key := keys[0]
+		return fmt.Errorf("sentry: reserved field %q has unsupported value %#v (%T)", key, df.data[key], df.data[key])
+	}
+	return nil
+}
+
+// checkStrictSize returns an error if the packet, or one of the parts it was
+// split into, would be rejected by the sentry server.
+func checkStrictSize(packet *raven.Packet, parts []*raven.Packet) error {
+	if len(parts) == 0 {
+		parts = []*raven.Packet{packet}
+	}
+	for _, p := range parts {
+		b, err := p.JSON()
+		if err != nil {
+			return fmt.Errorf("sentry: cannot serialize packet: %v", err)
+		}
+		if len(b) > maxPacketSize {
+			return fmt.Errorf("sentry: packet size %d exceeds the limit of %d bytes", len(b), maxPacketSize)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetStrict(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		fields      logrus.Fields
+		isSuccess   bool
+		description string
+	}{
+		{logrus.Fields{}, true, "no fields"},
+		{logrus.Fields{"tags": raven.Tags{{Key: "k", Value: "v"}}}, true, "valid tags"},
+		{logrus.Fields{"fingerprint": []string{"a"}}, true, "valid fingerprint"},
+		{logrus.Fields{"error": fmt.Errorf("err")}, true, "valid error"},
+		{logrus.Fields{"tags": map[string]string{"k": "v"}}, false, "invalid tags type"},
+		{logrus.Fields{"fingerprint": "a"}, false, "invalid fingerprint type"},
+		{logrus.Fields{"logger": 1}, false, "invalid logger type"},
+		{logrus.Fields{"server_name": true}, false, "invalid server_name type"},
+		{logrus.Fields{"event_id": "not-a-uuid"}, false, "invalid event_id format"},
+		{logrus.Fields{"http_request": "GET /"}, false, "invalid http_request type"},
+		{logrus.Fields{"user": "name"}, false, "invalid user type"},
+		{logrus.Fields{"error": "err"}, false, "invalid error type"},
+		{logrus.Fields{"large": strings.Repeat("a", maxPacketSize)}, false, "oversize packet"},
+		{logrus.Fields{"error": nil}, true, "nil error"},
+		{logrus.Fields{"error": (*nilError)(nil)}, true, "typed nil error"},
+		{logrus.Fields{"user": (*raven.User)(nil)}, true, "nil user"},
+	}
+
+	hook, err := NewSentryHook("", []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+
+	for _, tt := range tests {
+		target := tt.description
+		entry := &logrus.Entry{
+			Data:  tt.fields,
+			Level: logrus.ErrorLevel,
+		}
+
+		hook.SetStrict(false)
+		a.NoError(hook.Fire(entry), "non-strict hook should never fail: %s", target)
+
+		hook.SetStrict(true)
+		err := hook.Fire(entry)
+		switch {
+		case !tt.isSuccess:
+			a.Error(err, target)
+		case tt.isSuccess:
+			a.NoError(err, target)
+		}
+	}
+}
+
+func TestSetStrictSplitting(t *testing.T) {
+	a := assert.New(t)
+
+	hook, err := NewSentryHook("", []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.SetStrict(true)
+	hook.EnableEventSplitting(3)
+
+	entry := &logrus.Entry{
+		Data: logrus.Fields{
+			"first":  strings.Repeat("a", maxPacketSize/2),
+			"second": strings.Repeat("b", maxPacketSize/2),
+		},
+		Level: logrus.ErrorLevel,
+	}
+	a.NoError(hook.Fire(entry), "split packets should be checked part by part")
+
+	entry = &logrus.Entry{
+		Message: strings.Repeat("a", maxPacketSize),
+		Level:   logrus.ErrorLevel,
+	}
+	a.Error(hook.Fire(entry), "packets which can not be split should be checked")
+}
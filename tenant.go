@@ -0,0 +1,163 @@
+package logrus_sentry
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultMaxTenantClients is the default number of clients of resolved DSNs
+// kept by the hook.
+const defaultMaxTenantClients = 100
+
+// tenantClients caches the clients of the DSNs returned by a resolver.
+type tenantClients struct {
+	resolve func(entry *logrus.Entry) (string, bool)
+	max     int
+
+	mu      sync.Mutex
+	clients map[string]*tenantClient
+	lru     *list.List // of *tenantClient, the most recently used first
+}
+
+type tenantClient struct {
+	client  *raven.Client
+	dsn     string
+	elem    *list.Element
+	refs    int  // number of entries being captured with the client
+	evicted bool // whether the client is closed once refs drops to zero
+}
+
+// WithDSNResolver sends each entry to the DSN returned by resolve, e.g. the
+// sentry project of the tenant named by a field of the entry, so that shared
+// services report the errors of each tenant to its own project. The entries
+// for which resolve returns false are sent to the DSN of the hook, and the
+// entries matching a route of the hook to the route.
+//
+// A client is created for each DSN, with the settings of the hook, and up to
+// maxClients clients are kept; the least recently used one is closed once its
+// events are sent. A maxClients of zero defaults to 100. When a client can not
+// be created for a DSN, the error is passed to the error handlers and the
+// entry is sent to the DSN of the hook. A nil resolve removes the resolver.
+func (hook *SentryHook) WithDSNResolver(resolve func(entry *logrus.Entry) (dsn string, ok bool), maxClients int) {
+	if maxClients <= 0 {
+		maxClients = defaultMaxTenantClients
+	}
+
+	hook.settingsMu.Lock()
+	defer hook.settingsMu.Unlock()
+	if t := hook.tenants; t != nil {
+		t.mu.Lock()
+		for t.lru.Len() != 0 {
+			t.evict(t.lru.Back().Value.(*tenantClient))
+		}
+		t.mu.Unlock()
+	}
+	if resolve == nil {
+		hook.tenants = nil
+		return
+	}
+	hook.tenants = &tenantClients{
+		resolve: resolve,
+		max:     maxClients,
+		clients: make(map[string]*tenantClient),
+		lru:     list.New(),
+	}
+}
+
+// tenantClient returns the client of dsn, creating it with the settings of
+// the hook if it is not cached. The caller must release it.
+func (hook *SentryHook) tenantClient(t *tenantClients, dsn string) (*tenantClient, error) {
+	if tc := t.get(dsn); tc != nil {
+		return tc, nil
+	}
+
+	// the settings must not change until the client is cached, for it to
+	// get them all
+	hook.settingsMu.Lock()
+	defer hook.settingsMu.Unlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if tc, ok := t.clients[dsn]; ok {
+		tc.refs++
+		t.lru.MoveToFront(tc.elem)
+		return tc, nil
+	}
+
+	client, err := raven.NewWithTags(dsn, hook.client.Tags)
+	if err != nil {
+		return nil, err
+	}
+	hook.instrumentClient(client)
+	for _, s := range hook.clientSettings {
+		if err := s.apply(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	tc := &tenantClient{client: client, dsn: dsn, refs: 1}
+	tc.elem = t.lru.PushFront(tc)
+	t.clients[dsn] = tc
+	for t.lru.Len() > t.max {
+		t.evict(t.lru.Back().Value.(*tenantClient))
+	}
+	return tc, nil
+}
+
+// get returns the cached client of dsn, or nil. The caller must release it.
+func (t *tenantClients) get(dsn string) *tenantClient {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc, ok := t.clients[dsn]
+	if !ok {
+		return nil
+	}
+	tc.refs++
+	t.lru.MoveToFront(tc.elem)
+	return tc
+}
+
+// release marks the end of a capture with tc, closing it if it was evicted
+// meanwhile.
+func (t *tenantClients) release(tc *tenantClient) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	tc.refs--
+	if tc.evicted && tc.refs == 0 {
+		go closeTenantClient(tc.client)
+	}
+}
+
+// cached returns the cached clients.
+func (t *tenantClients) cached() []*raven.Client {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	clients := make([]*raven.Client, 0, t.lru.Len())
+	for e := t.lru.Front(); e != nil; e = e.Next() {
+		clients = append(clients, e.Value.(*tenantClient).client)
+	}
+	return clients
+}
+
+// evict removes tc from the cache, and closes it unless entries are being
+// captured with it. t.mu must be held.
+func (t *tenantClients) evict(tc *tenantClient) {
+	t.lru.Remove(tc.elem)
+	delete(t.clients, tc.dsn)
+	tc.evicted = true
+	if tc.refs == 0 {
+		go closeTenantClient(tc.client)
+	}
+}
+
+// closeTenantClient closes an evicted client once its events are sent.
+func closeTenantClient(client *raven.Client) {
+	client.Close()
+	flushClientBatch(client)
+}
@@ -0,0 +1,81 @@
+package logrus_sentry
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDSNResolver(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(fallbackDSN string, fallbackCh <-chan *resultPacket) {
+		WithTestDSN(t, func(acmeDSN string, acmeCh <-chan *resultPacket) {
+			WithTestDSN(t, func(globexDSN string, globexCh <-chan *resultPacket) {
+				dsns := map[string]string{
+					"acme":    acmeDSN,
+					"globex":  globexDSN,
+					"invalid": "invalid dsn",
+				}
+				logger := getTestLogger()
+				hook, err := NewSentryHook(fallbackDSN, []logrus.Level{
+					logrus.ErrorLevel,
+				})
+				a.NoError(err, "NewSentryHook should be NoError")
+
+				var handled []error
+				hook.AddErrorHandler(func(entry *logrus.Entry, err error) {
+					if err != nil {
+						handled = append(handled, err)
+					}
+				})
+				hook.WithDSNResolver(func(entry *logrus.Entry) (string, bool) {
+					tenant, ok := entry.Data["tenant"].(string)
+					if !ok {
+						return "", false
+					}
+					dsn, ok := dsns[tenant]
+					return dsn, ok
+				}, 1)
+				hook.SetEnvironment("test")
+				logger.Hooks.Add(hook)
+
+				logger.WithField("tenant", "acme").Error("acme message")
+				packet := <-acmeCh
+				a.Equal("acme message", packet.Message, "entry must be sent to the resolved DSN")
+				a.Equal("test", packet.Environment, "tenant clients must get the settings of the hook")
+
+				hook.SetRelease("v1")
+				logger.WithField("tenant", "acme").Error("acme message")
+				packet = <-acmeCh
+				a.Equal("v1", packet.Release, "setters must apply to the cached tenant clients")
+
+				logger.WithField("tenant", "globex").Error("globex message")
+				packet = <-globexCh
+				a.Equal("globex message", packet.Message)
+				a.Equal("v1", packet.Release, "tenant clients must get the settings of the hook")
+				a.Len(hook.clients(), 2, "the least recently used tenant client must be evicted")
+
+				logger.WithField("tenant", "acme").Error("acme message")
+				packet = <-acmeCh
+				a.Equal("acme message", packet.Message, "evicted tenant clients must be created again")
+
+				logger.WithField("tenant", "unknown").Error("unknown message")
+				packet = <-fallbackCh
+				a.Equal("unknown message", packet.Message, "unresolved entry must be sent to the fallback")
+
+				logger.WithField("tenant", "invalid").Error("invalid message")
+				packet = <-fallbackCh
+				a.Equal("invalid message", packet.Message, "entry with an invalid DSN must be sent to the fallback")
+				a.Len(handled, 1, "invalid DSN must be reported to the error handlers")
+
+				hook.WithDSNResolver(nil, 0)
+				a.Len(hook.clients(), 1)
+				logger.WithField("tenant", "acme").Error("acme message")
+				packet = <-fallbackCh
+				a.Equal("acme message", packet.Message)
+			})
+		})
+	})
+}
@@ -0,0 +1,150 @@
+package logrus_sentry
+
+import (
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	logrusPackage = reflect.TypeOf(logrus.Entry{}).PkgPath()
+	hookPackage   = reflect.TypeOf(SentryHook{}).PkgPath()
+)
+
+// callSiteThreshold delays reporting the call sites matching pattern until
+// they fired count times within window.
+type callSiteThreshold struct {
+	pattern string
+	count   int
+	window  time.Duration
+}
+
+// callSiteCounter records the recent occurrences of each call site.
+type callSiteCounter struct {
+	mu    sync.Mutex
+	sites map[string]*callSiteHits
+	// swept is the time the idle call sites were last forgotten
+	swept time.Time
+}
+
+// callSiteHits holds the most recent occurrences of a call site, at most as
+// many as the count of its threshold, the oldest first.
+type callSiteHits struct {
+	times  []time.Time
+	window time.Duration
+}
+
+// AddCallSiteThreshold only reports the entries logged from the call sites
+// matching pattern once they fired count times within window. It suits
+// known-flaky integrations whose first errors are expected and retried.
+//
+// pattern is matched against the end of the call site "file:line", e.g.
+// "payments/client.go:120", or of its file name, e.g. "payments/client.go".
+// The call site is entry.Caller when the logger reports it, and the first
+// frame outside of logrus and this package otherwise.
+func (hook *SentryHook) AddCallSiteThreshold(pattern string, count int, window time.Duration) {
+	hook.thresholds = append(hook.thresholds, callSiteThreshold{
+		pattern: pattern,
+		count:   count,
+		window:  window,
+	})
+}
+
+// allowCallSite records the entry's call site and reports whether the entry
+// reached the threshold of its call site.
+func (hook *SentryHook) allowCallSite(entry *logrus.Entry) bool {
+	file, line := callSite(entry)
+	if file == "" {
+		return true
+	}
+	site := file + ":" + strconv.Itoa(line)
+
+	for _, th := range hook.thresholds {
+		if !strings.HasSuffix(site, th.pattern) && !strings.HasSuffix(file, th.pattern) {
+			continue
+		}
+		now := entry.Time
+		if now.IsZero() {
+			now = time.Now()
+		}
+		return hook.callSites.hit(site, now, th.count, th.window) >= th.count
+	}
+	return true
+}
+
+// hit records an occurrence of site and returns the number of occurrences
+// within window, up to max. The call sites without occurrences within their
+// window are forgotten at most once per window.
+func (c *callSiteCounter) hit(site string, now time.Time, max int, window time.Duration) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sites == nil {
+		c.sites = make(map[string]*callSiteHits)
+	}
+	if now.Sub(c.swept) >= window {
+		c.swept = now
+		for key, h := range c.sites {
+			if now.Sub(h.times[len(h.times)-1]) >= h.window {
+				delete(c.sites, key)
+			}
+		}
+	}
+
+	h, ok := c.sites[site]
+	if !ok {
+		h = &callSiteHits{}
+		c.sites[site] = h
+	}
+	h.window = window
+	i := 0
+	for i < len(h.times) && now.Sub(h.times[i]) >= window {
+		i++
+	}
+	if n := len(h.times) - i + 1; max > 0 && n > max {
+		i += n - max
+	}
+	h.times = append(h.times[:0], h.times[i:]...)
+	h.times = append(h.times, now)
+	return len(h.times)
+}
+
+// callSite returns the location the entry was logged from.
+func callSite(entry *logrus.Entry) (string, int) {
+	frame := callerFrame(entry)
+	return frame.File, frame.Line
+}
+
+// callerFrame returns the frame the entry was logged from.
+func callerFrame(entry *logrus.Entry) runtime.Frame {
+	if entry.Caller != nil {
+		return *entry.Caller
+	}
+
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !inPackage(frame.Function, logrusPackage) && !inPackage(frame.Function, hookPackage) {
+			return frame
+		}
+		if !more {
+			return runtime.Frame{}
+		}
+	}
+}
+
+// inPackage reports whether the function belongs to the package pkg.
+func inPackage(function, pkg string) bool {
+	if !strings.HasPrefix(function, pkg) {
+		return false
+	}
+	rest := function[len(pkg):]
+	return strings.HasPrefix(rest, ".") && !strings.Contains(rest[1:], "/")
+}
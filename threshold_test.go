@@ -0,0 +1,70 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddCallSiteThreshold(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		logger.SetReportCaller(true)
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.AddCallSiteThreshold("threshold_test.go", 3, time.Minute)
+		logger.Hooks.Add(hook)
+
+		for i := 1; i <= 4; i++ {
+			logger.Error(fmt.Sprintf("flaky %d", i))
+			if i >= 3 {
+				a.Equal(fmt.Sprintf("flaky %d", i), (<-pch).Message, "call site must be reported from its third occurrence")
+			}
+		}
+	})
+}
+
+func TestCallSiteCounterHit(t *testing.T) {
+	a := assert.New(t)
+
+	var c callSiteCounter
+	now := time.Now()
+	a.Equal(1, c.hit("a.go:1", now, 5, time.Second))
+	a.Equal(2, c.hit("a.go:1", now.Add(500*time.Millisecond), 5, time.Second))
+	a.Equal(1, c.hit("b.go:1", now, 5, time.Second), "call sites must be counted separately")
+	a.Equal(2, c.hit("a.go:1", now.Add(1200*time.Millisecond), 5, time.Second), "occurrences out of the window must be forgotten")
+	a.NotContains(c.sites, "b.go:1", "idle call sites must be forgotten")
+
+	for i := 0; i < 10; i++ {
+		c.hit("c.go:1", now.Add(1300*time.Millisecond), 5, time.Second)
+	}
+	a.Len(c.sites["c.go:1"].times, 5, "occurrences beyond the count must not be kept")
+}
+
+func TestInPackage(t *testing.T) {
+	a := assert.New(t)
+
+	tests := []struct {
+		function string
+		pkg      string
+		expected bool
+	}{
+		{"github.com/sirupsen/logrus.(*Entry).Error", "github.com/sirupsen/logrus", true},
+		{"github.com/sirupsen/logrus.(*Logger).Log", "github.com/sirupsen/logrus", true},
+		{"github.com/sirupsen/logrus/hooks/test.(*Hook).Fire", "github.com/sirupsen/logrus", false},
+		{"github.com/sirupsen/logrusx.Fn", "github.com/sirupsen/logrus", false},
+		{"main.main", "github.com/sirupsen/logrus", false},
+	}
+
+	for _, tt := range tests {
+		a.Equal(tt.expected, inPackage(tt.function, tt.pkg), tt.function)
+	}
+}
@@ -0,0 +1,169 @@
+package logrus_sentry
+
+import (
+	"container/list"
+	"crypto/sha1"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// extraSuppressedCount is the extra counting the events of a fingerprint
+	// suppressed since its previous event.
+	extraSuppressedCount = "suppressed_count"
+
+	// maxThrottleKeys bounds the number of fingerprints tracked before the
+	// idle ones, then the least recently used ones, are forgotten.
+	maxThrottleKeys = 10000
+)
+
+// throttle is a token bucket per fingerprint.
+type throttle struct {
+	rate int
+	per  time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]*throttleBucket
+	lru     *list.List // of *throttleBucket, the most recently used first
+	// pruned is the time of the last prune
+	pruned time.Time
+	// changes counts the changes of the buckets, for the throttle store
+	changes uint64
+}
+
+type throttleBucket struct {
+	key        string
+	elem       *list.Element
+	tokens     float64
+	last       time.Time
+	suppressed int
+}
+
+func newThrottle(rate int, per time.Duration) *throttle {
+	return &throttle{
+		rate:    rate,
+		per:     per,
+		buckets: make(map[string]*throttleBucket),
+		lru:     list.New(),
+	}
+}
+
+// SetThrottle sends at most rate events of each fingerprint per period, with
+// bursts of up to rate events. The events in excess are counted, and their
+// count is sent in the suppressed_count extra of the next event of the same
+// fingerprint. Events without fingerprint are throttled by culprit and
+// message. A rate of zero disables the throttle.
+func (hook *SentryHook) SetThrottle(rate int, per time.Duration) {
+	if rate <= 0 || per <= 0 {
+		if p := hook.throttleStore; p != nil {
+			p.attach(nil)
+		}
+		hook.throttle = nil
+		return
+	}
+	t := newThrottle(rate, per)
+	if p := hook.throttleStore; p != nil {
+		p.attach(t)
+	}
+	hook.throttle = t
+}
+
+// throttleKey returns the key the packet is throttled by.
+func throttleKey(packet *raven.Packet, entry *logrus.Entry) string {
+	if len(packet.Fingerprint) != 0 {
+		return strings.Join(packet.Fingerprint, "\x00")
+	}
+	return packet.Culprit + "\x00" + entry.Message
+}
+
+// allow takes a token of key, and returns whether one was available and the
+// number of events suppressed since the previous allowed one. The buckets
+// are keyed by the hash of key, so that the messages are not persisted.
+func (t *throttle) allow(key string, now time.Time) (bool, int) {
+	key = hashKey(key)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.changes++
+
+	b, ok := t.buckets[key]
+	if ok {
+		t.lru.MoveToFront(b.elem)
+	} else {
+		if len(t.buckets) >= maxThrottleKeys {
+			t.evict(now)
+		}
+		b = &throttleBucket{key: key, tokens: float64(t.rate), last: now}
+		t.add(b)
+	}
+	b.refill(now, t.rate, t.per)
+
+	if b.tokens < 1 {
+		b.suppressed++
+		return false, 0
+	}
+	b.tokens--
+	suppressed := b.suppressed
+	b.suppressed = 0
+	return true, suppressed
+}
+
+// add tracks b as the most recently used bucket. t.mu must be held.
+func (t *throttle) add(b *throttleBucket) {
+	if prev, ok := t.buckets[b.key]; ok {
+		t.remove(prev)
+	}
+	t.buckets[b.key] = b
+	b.elem = t.lru.PushFront(b)
+}
+
+// remove forgets b. t.mu must be held.
+func (t *throttle) remove(b *throttleBucket) {
+	delete(t.buckets, b.key)
+	t.lru.Remove(b.elem)
+}
+
+// evict makes room for a new bucket. It prunes the buckets at most once per
+// time to refill a token, since pruning walks all of them, then forgets the
+// least recently used buckets if pruning did not free any. t.mu must be held.
+func (t *throttle) evict(now time.Time) {
+	if now.Sub(t.pruned) >= t.per/time.Duration(t.rate) {
+		t.pruned = now
+		t.prune(now)
+	}
+	for len(t.buckets) >= maxThrottleKeys {
+		t.remove(t.lru.Back().Value.(*throttleBucket))
+	}
+}
+
+// prune forgets the buckets which are full and have no suppressed events,
+// since they are equivalent to new ones. t.mu must be held.
+func (t *throttle) prune(now time.Time) {
+	for _, b := range t.buckets {
+		b.refill(now, t.rate, t.per)
+		if b.suppressed == 0 && b.tokens >= float64(t.rate) {
+			t.remove(b)
+		}
+	}
+}
+
+// hashKey returns the hex encoded SHA-1 of key.
+func hashKey(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (b *throttleBucket) refill(now time.Time, rate int, per time.Duration) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += float64(rate) * float64(elapsed) / float64(per)
+		if b.tokens > float64(rate) {
+			b.tokens = float64(rate)
+		}
+		b.last = now
+	}
+}
@@ -0,0 +1,221 @@
+package logrus_sentry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// throttleSaveInterval is the interval of the saves of the throttle state in
+// the background.
+const throttleSaveInterval = time.Second
+
+// ThrottleBucket is the persisted state of the throttle of a fingerprint.
+type ThrottleBucket struct {
+	Tokens     float64   `json:"tokens"`
+	Last       time.Time `json:"last"`
+	Suppressed int       `json:"suppressed"`
+}
+
+// ThrottleStore persists the state of the throttle across restarts, so that
+// a crash looping process does not reset its suppression windows on every
+// restart. The keys of the buckets are opaque hashes.
+type ThrottleStore interface {
+	Load() (map[string]ThrottleBucket, error)
+	Save(buckets map[string]ThrottleBucket) error
+}
+
+// fileThrottleStore stores the state of the throttle in a JSON file.
+type fileThrottleStore struct {
+	path string
+}
+
+// NewFileThrottleStore returns a ThrottleStore keeping the state of the
+// throttle in the JSON file at path. The file must outlive the process, e.g.
+// on a volume kept across container restarts.
+func NewFileThrottleStore(path string) ThrottleStore {
+	return &fileThrottleStore{path: path}
+}
+
+// Load returns the buckets saved in the file, or none if it does not exist.
+func (s *fileThrottleStore) Load() (map[string]ThrottleBucket, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var buckets map[string]ThrottleBucket
+	if err := json.Unmarshal(b, &buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// Save replaces the content of the file with buckets.
+func (s *fileThrottleStore) Save(buckets map[string]ThrottleBucket) error {
+	b, err := json.Marshal(buckets)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// throttlePersistence saves the state of a throttle to its store.
+type throttlePersistence struct {
+	store ThrottleStore
+	stop  chan struct{}
+
+	mu sync.Mutex // guards throttle and loaded, and serializes the saves
+	// throttle is the throttle whose state is saved
+	throttle *throttle
+	// saved is the number of changes of the throttle when it was last saved
+	saved uint64
+	// loaded holds the buckets loaded from the store until a throttle is set
+	loaded map[string]ThrottleBucket
+}
+
+// SetThrottleStore restores the state of the throttle set by SetThrottle,
+// before or after this call, from store. The state is then saved to store in
+// the background every second if it changed, and on Flush: flush the hook
+// before the process exits, e.g. with logrus.RegisterExitHandler(hook.Flush)
+// for the fatal events. Errors while saving the state in the background are
+// passed to the error handlers of the hook. A nil store stops the
+// persistence.
+//
+// It should be called when the hook is created, before any event.
+func (hook *SentryHook) SetThrottleStore(store ThrottleStore) error {
+	if p := hook.throttleStore; p != nil {
+		close(p.stop)
+		hook.throttleStore = nil
+	}
+	if store == nil {
+		return nil
+	}
+	buckets, err := store.Load()
+	if err != nil {
+		return err
+	}
+	p := &throttlePersistence{
+		store:  store,
+		stop:   make(chan struct{}),
+		loaded: buckets,
+	}
+	p.attach(hook.throttle)
+	hook.throttleStore = p
+	go p.run(hook)
+	return nil
+}
+
+// attach makes p save the state of t, restoring the loaded buckets into it.
+func (p *throttlePersistence) attach(t *throttle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.throttle = t
+	p.saved = 0
+	if t != nil && p.loaded != nil {
+		t.restore(p.loaded)
+		p.loaded = nil
+	}
+}
+
+// run saves the state of the throttle in the background until p is stopped.
+func (p *throttlePersistence) run(hook *SentryHook) {
+	ticker := time.NewTicker(throttleSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.save(false); err != nil {
+				entry := logrus.NewEntry(logrus.StandardLogger())
+				entry.Level = logrus.ErrorLevel
+				entry.Message = "sentry: cannot save the throttle state"
+				for _, handlerFn := range hook.errorHandlers {
+					handlerFn(entry, err)
+				}
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// restore adds buckets to the buckets of t, the most recently refilled ones
+// being the most recently used.
+func (t *throttle) restore(buckets map[string]ThrottleBucket) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	keys := make([]string, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return buckets[keys[i]].Last.Before(buckets[keys[j]].Last)
+	})
+	for _, key := range keys {
+		b := buckets[key]
+		t.add(&throttleBucket{
+			key:        key,
+			tokens:     b.Tokens,
+			last:       b.Last,
+			suppressed: b.Suppressed,
+		})
+	}
+	if len(t.buckets) > maxThrottleKeys {
+		now := time.Now()
+		t.pruned = now
+		t.prune(now)
+		for len(t.buckets) > maxThrottleKeys {
+			t.remove(t.lru.Back().Value.(*throttleBucket))
+		}
+	}
+	t.changes++
+}
+
+// snapshot returns a copy of the buckets of t and the number of its changes,
+// or false if it has saved changes, unless force is set.
+func (t *throttle) snapshot(saved uint64, force bool) (map[string]ThrottleBucket, uint64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.changes == saved && !force {
+		return nil, 0, false
+	}
+	buckets := make(map[string]ThrottleBucket, len(t.buckets))
+	for key, b := range t.buckets {
+		buckets[key] = ThrottleBucket{
+			Tokens:     b.tokens,
+			Last:       b.last,
+			Suppressed: b.suppressed,
+		}
+	}
+	return buckets, t.changes, true
+}
+
+// save saves the state of the throttle if it changed since the previous
+// successful save or force is set. A failed save is retried on the next one.
+func (p *throttlePersistence) save(force bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.throttle == nil {
+		return nil
+	}
+	buckets, changes, ok := p.throttle.snapshot(p.saved, force)
+	if !ok {
+		return nil
+	}
+	if err := p.store.Save(buckets); err != nil {
+		return err
+	}
+	p.saved = changes
+	return nil
+}
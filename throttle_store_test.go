@@ -0,0 +1,118 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileThrottleStore(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "throttle")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	store := NewFileThrottleStore(filepath.Join(dir, "state", "throttle.json"))
+
+	buckets, err := store.Load()
+	a.NoError(err, "a missing file should be an empty state")
+	a.Empty(buckets)
+
+	saved := map[string]ThrottleBucket{
+		"a": {Tokens: 0.5, Last: time.Now().Round(0), Suppressed: 3},
+	}
+	a.NoError(store.Save(saved))
+	buckets, err = store.Load()
+	a.NoError(err)
+	a.Equal(saved["a"].Tokens, buckets["a"].Tokens)
+	a.True(saved["a"].Last.Equal(buckets["a"].Last))
+	a.Equal(saved["a"].Suppressed, buckets["a"].Suppressed)
+}
+
+func TestSetThrottleStore(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "throttle")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "throttle.json")
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		fields := logrus.Fields{"fingerprint": []string{"throttled"}}
+
+		// each hook stands for a restarted process
+		restart := func(storeFirst bool) *SentryHook {
+			hook, err := NewSentryHook(dsn, []logrus.Level{
+				logrus.ErrorLevel,
+			})
+			a.NoError(err, "NewSentryHook should be NoError")
+			if storeFirst {
+				a.NoError(hook.SetThrottleStore(NewFileThrottleStore(path)))
+				hook.SetThrottle(1, time.Hour)
+			} else {
+				hook.SetThrottle(1, time.Hour)
+				a.NoError(hook.SetThrottleStore(NewFileThrottleStore(path)))
+			}
+			return hook
+		}
+
+		hook := restart(true)
+		logger := getTestLogger()
+		logger.Hooks.Add(hook)
+		logger.WithFields(fields).Error(message)
+		<-pch
+		logger.WithFields(fields).Error(message)
+		a.Equal(uint64(1), hook.Stats().DroppedByThrottle)
+		hook.Flush()
+		data, err := ioutil.ReadFile(path)
+		a.NoError(err)
+		a.NotContains(string(data), "throttled", "the keys should be hashed")
+
+		for _, storeFirst := range []bool{true, false} {
+			hook = restart(storeFirst)
+			logger = getTestLogger()
+			logger.Hooks.Add(hook)
+			logger.WithFields(fields).Error(message)
+			a.Equal(uint64(1), hook.Stats().DroppedByThrottle, "the suppression window should survive restarts")
+			hook.Flush()
+		}
+	})
+}
+
+// failingThrottleStore fails its saves while err is set.
+type failingThrottleStore struct {
+	err   error
+	saves int
+}
+
+func (s *failingThrottleStore) Load() (map[string]ThrottleBucket, error) {
+	return nil, nil
+}
+
+func (s *failingThrottleStore) Save(buckets map[string]ThrottleBucket) error {
+	s.saves++
+	return s.err
+}
+
+func TestThrottleStoreRetry(t *testing.T) {
+	a := assert.New(t)
+
+	store := &failingThrottleStore{err: errors.New("disk full")}
+	p := &throttlePersistence{store: store}
+	th := newThrottle(1, time.Hour)
+	p.attach(th)
+	th.allow("key", time.Now())
+
+	a.Error(p.save(false))
+	a.Error(p.save(false), "a failed save should be retried")
+	store.err = nil
+	a.NoError(p.save(false))
+	a.NoError(p.save(false))
+	a.Equal(3, store.saves, "the state should not be saved again once saved")
+}
@@ -0,0 +1,93 @@
+package logrus_sentry
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleAllow(t *testing.T) {
+	a := assert.New(t)
+
+	th := newThrottle(2, time.Minute)
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		allowed, suppressed := th.allow("a", now)
+		a.True(allowed, "burst should be allowed")
+		a.Equal(0, suppressed)
+	}
+	for i := 0; i < 3; i++ {
+		allowed, _ := th.allow("a", now)
+		a.False(allowed, "events over the rate should be suppressed")
+	}
+	allowed, _ := th.allow("b", now)
+	a.True(allowed, "fingerprints should be throttled separately")
+
+	allowed, suppressed := th.allow("a", now.Add(30*time.Second))
+	a.True(allowed, "tokens should be refilled")
+	a.Equal(3, suppressed)
+	allowed, _ = th.allow("a", now.Add(30*time.Second))
+	a.False(allowed)
+}
+
+func TestThrottleEviction(t *testing.T) {
+	a := assert.New(t)
+
+	th := newThrottle(1, time.Hour)
+	now := time.Now()
+
+	// every bucket has suppressed events, so none can be pruned
+	for i := 0; i < maxThrottleKeys; i++ {
+		th.allow(strconv.Itoa(i), now)
+		th.allow(strconv.Itoa(i), now)
+	}
+	allowed, _ := th.allow("0", now)
+	a.False(allowed)
+
+	allowed, _ = th.allow("new", now)
+	a.True(allowed)
+	a.Len(th.buckets, maxThrottleKeys, "the number of buckets should be bounded")
+	allowed, _ = th.allow("0", now)
+	a.False(allowed, "the recently used buckets should be kept")
+	allowed, _ = th.allow("1", now)
+	a.True(allowed, "the least recently used bucket should be evicted")
+	a.Len(th.buckets, maxThrottleKeys)
+}
+
+func TestSetThrottle(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetThrottle(1, 100*time.Millisecond)
+		logger.Hooks.Add(hook)
+
+		fields := logrus.Fields{"fingerprint": []string{"throttled"}}
+		logger.WithFields(fields).Error(message)
+		packet := <-pch
+		a.NotContains(packet.Extra, extraSuppressedCount)
+
+		logger.WithFields(fields).Error(message)
+		logger.WithFields(fields).Error(message)
+		a.Equal(uint64(2), hook.Stats().DroppedByThrottle)
+
+		time.Sleep(150 * time.Millisecond)
+		logger.WithFields(fields).Error(message)
+		packet = <-pch
+		a.Equal(float64(2), packet.Extra[extraSuppressedCount])
+
+		hook.SetThrottle(0, 0)
+		logger.WithFields(fields).Error(message)
+		packet = <-pch
+		a.NotContains(packet.Extra, extraSuppressedCount)
+	})
+}
@@ -0,0 +1,53 @@
+package logrus_sentry
+
+import (
+	"context"
+
+	"github.com/musqdp/raven-go"
+)
+
+const (
+	tagSpanID = "span_id"
+
+	contextTrace = "trace"
+)
+
+// TraceExtractor returns the IDs of the tracing span active in ctx.
+// It adapts a tracing library such as OpenTelemetry without the hook
+// depending on it.
+type TraceExtractor func(ctx context.Context) (traceID, spanID string, ok bool)
+
+// SetTraceExtractor sets the function used to correlate events with the
+// tracing span active in the entry's context. The IDs are set as the
+// trace_id and span_id tags and as the trace context of the event.
+func (hook *SentryHook) SetTraceExtractor(fn TraceExtractor) {
+	hook.traceExtractor = fn
+}
+
+// applyTrace sets the IDs of the span active in ctx on the packet.
+func (hook *SentryHook) applyTrace(ctx context.Context, packet *raven.Packet) {
+	if hook.traceExtractor == nil {
+		return
+	}
+	traceID, spanID, ok := hook.traceExtractor(ctx)
+	if !ok || traceID == "" {
+		return
+	}
+
+	tags := map[string]string{
+		tagTraceID: traceID,
+	}
+	trace := map[string]interface{}{
+		"type":     contextTrace,
+		"trace_id": traceID,
+	}
+	if spanID != "" {
+		tags[tagSpanID] = spanID
+		trace["span_id"] = spanID
+	}
+	for _, tag := range packet.Tags {
+		delete(tags, tag.Key)
+	}
+	packet.AddTags(tags)
+	setPacketContext(packet, contextTrace, trace)
+}
@@ -0,0 +1,22 @@
+//go:build otel
+// +build otel
+
+package logrus_sentry
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OpenTelemetryTraceExtractor extracts the IDs of the OpenTelemetry span
+// active in ctx. It is only built with the otel build tag:
+//
+//	hook.SetTraceExtractor(logrus_sentry.OpenTelemetryTraceExtractor)
+func OpenTelemetryTraceExtractor(ctx context.Context) (traceID, spanID string, ok bool) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return "", "", false
+	}
+	return sc.TraceID().String(), sc.SpanID().String(), true
+}
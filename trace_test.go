@@ -0,0 +1,72 @@
+package logrus_sentry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+func testTraceExtractor(ctx context.Context) (string, string, bool) {
+	ids, ok := ctx.Value(spanKey{}).([2]string)
+	return ids[0], ids[1], ok
+}
+
+func TestSetTraceExtractor(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetTraceExtractor(testTraceExtractor)
+		logger.Hooks.Add(hook)
+
+		ctx := context.WithValue(context.Background(), spanKey{}, [2]string{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+		logger.WithContext(ctx).Error(message)
+		packet := <-pch
+		a.Contains(packet.Tags, raven.Tag{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"})
+		a.Contains(packet.Tags, raven.Tag{Key: "span_id", Value: "00f067aa0ba902b7"})
+		a.Equal(map[string]interface{}{
+			"type":     "trace",
+			"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":  "00f067aa0ba902b7",
+		}, packet.Contexts["trace"])
+
+		logger.WithContext(context.Background()).Error(message)
+		packet = <-pch
+		a.NotContains(packet.Contexts, "trace", "trace context must not be set without an active span")
+	})
+}
+
+func TestContextsField(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		logger := getTestLogger()
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		hook.SetTraceExtractor(testTraceExtractor)
+		logger.Hooks.Add(hook)
+
+		ctx := context.WithValue(context.Background(), spanKey{}, [2]string{"4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"})
+		logger.WithContext(ctx).WithField("contexts", map[string]interface{}{
+			"app":   map[string]interface{}{"type": "app", "app_name": "api"},
+			"trace": map[string]interface{}{"type": "trace", "trace_id": "override"},
+		}).Error(message)
+		packet := <-pch
+		a.Equal(map[string]interface{}{"type": "app", "app_name": "api"}, packet.Contexts["app"])
+		a.Equal(map[string]interface{}{"type": "trace", "trace_id": "override"}, packet.Contexts["trace"], "contexts field must override the hook contexts")
+		a.NotContains(packet.Extra, "contexts")
+	})
+}
@@ -0,0 +1,85 @@
+package logrus_sentry
+
+import (
+	"net/http"
+
+	"github.com/musqdp/raven-go"
+)
+
+// SetHTTPClient sets the HTTP client used to send events, e.g. to configure
+// a proxy, a custom CA bundle or client certificates for an on-premise
+// sentry. Its Timeout bounds every send, including those of asynchronous
+// hooks. It only applies to clients using the default raven transport.
+func (hook *SentryHook) SetHTTPClient(httpClient *http.Client) {
+	hook.httpClient = httpClient
+	_ = hook.configureClients("http_client", func(client *raven.Client) error {
+		setTransportHTTPClient(client.Transport, httpClient)
+		return nil
+	})
+	if r := hook.regions; r != nil {
+		r.mu.Lock()
+		r.httpClient = hook.probeHTTPClient()
+		r.mu.Unlock()
+	}
+	if s := hook.spool; s != nil {
+		s.mu.Lock()
+		s.httpClient = httpClient
+		s.mu.Unlock()
+	}
+}
+
+// SetHTTPTransport sets the HTTP transport used to send events, keeping the
+// defaults of http.Client.
+func (hook *SentryHook) SetHTTPTransport(transport http.RoundTripper) {
+	hook.SetHTTPClient(&http.Client{Transport: transport})
+}
+
+// setTransportHTTPClient sets the HTTP client of the raven transport wrapped
+// by transport, if any.
+func setTransportHTTPClient(transport raven.Transport, httpClient *http.Client) {
+	switch t := transport.(type) {
+	case *raven.HTTPTransport:
+		t.Client = httpClient
+	case *batchTransport:
+		t.httpClient = httpClient
+	case *spoolTransport:
+		setTransportHTTPClient(t.Transport, httpClient)
+	case *statsTransport:
+		setTransportHTTPClient(t.Transport, httpClient)
+	case *compatTransport:
+		setTransportHTTPClient(t.Transport, httpClient)
+	case *attachmentTransport:
+		setTransportHTTPClient(t.Transport, httpClient)
+	}
+}
+
+// probeHTTPClient returns the HTTP client used to probe regional endpoints.
+func (hook *SentryHook) probeHTTPClient() *http.Client {
+	c := &http.Client{Timeout: regionProbeTimeout}
+	if hook.httpClient != nil {
+		c.Transport = hook.httpClient.Transport
+	}
+	return c
+}
+
+// transportChain returns the chain of transports starting at transport, from
+// the outermost wrapper to the transport sending the events.
+func transportChain(transport raven.Transport) []raven.Transport {
+	var chain []raven.Transport
+	for transport != nil {
+		chain = append(chain, transport)
+		switch t := transport.(type) {
+		case *spoolTransport:
+			transport = t.Transport
+		case *statsTransport:
+			transport = t.Transport
+		case *compatTransport:
+			transport = t.Transport
+		case *attachmentTransport:
+			transport = t.Transport
+		default:
+			transport = nil
+		}
+	}
+	return chain
+}
@@ -0,0 +1,75 @@
+package logrus_sentry
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingRoundTripper struct {
+	count int32
+}
+
+func (rt *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&rt.count, 1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestSetHTTPTransport(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+		hook.Timeout = time.Second
+		rt := &countingRoundTripper{}
+		hook.SetHTTPTransport(rt)
+
+		logger := getTestLogger()
+		logger.Hooks.Add(hook)
+		logger.Error(message)
+
+		<-pch
+		a.Equal(int32(1), atomic.LoadInt32(&rt.count), "events should be sent with the transport")
+	})
+}
+
+func TestFireContextDone(t *testing.T) {
+	a := assert.New(t)
+
+	release := make(chan struct{})
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		<-release
+	})
+	defer s.Close()
+	defer close(release)
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = 10 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	entry := &logrus.Entry{
+		Data:    logrus.Fields{},
+		Time:    time.Now(),
+		Level:   logrus.ErrorLevel,
+		Message: message,
+		Context: ctx,
+	}
+
+	start := time.Now()
+	err = hook.Fire(entry)
+	a.Error(err, "Fire should stop waiting when the context is done")
+	a.True(time.Since(start) < time.Second)
+}
@@ -1,7 +1,9 @@
 package logrus_sentry
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io"
 	"strings"
 )
 
@@ -107,6 +109,18 @@ func (uuid uuid) noDashString() string {
 		b[:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
+// newEventID returns a random UUID4 in the no dash form used by Sentry,
+// or "" if the random source fails.
+func newEventID() string {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return ""
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // IETF variant
+	return uuid(b).noDashString()
+}
+
 // xvalues returns the value of a byte as a hexadecimal digit or 255.
 var xvalues = []byte{
 	255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255,
@@ -0,0 +1,97 @@
+package logrus_sentry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/musqdp/raven-go"
+)
+
+// defaultWarmUpTimeout bounds the warm up of the connections.
+const defaultWarmUpTimeout = 2 * time.Second
+
+// WarmUp establishes and validates the connections to the sentry servers of
+// the hook, so that the first event does not pay the DNS lookup and TLS
+// handshake in the request path. The connections are then kept by the HTTP
+// clients of the hook for the next events, until the server closes them. It
+// waits at most timeout, or 2 seconds if zero.
+//
+// A failure is not fatal: the hook connects again on the next event. The
+// error, e.g. a DNS failure or an invalid certificate, can be logged to
+// detect misconfigurations early.
+func (hook *SentryHook) WarmUp(timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultWarmUpTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	clients := hook.clients()
+	errs := make([]error, len(clients))
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		httpClient := transportHTTPClient(client.Transport)
+		if httpClient == nil || client.URL() == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, httpClient *http.Client, target string) {
+			defer wg.Done()
+			errs[i] = warmUp(ctx, httpClient, target)
+		}(i, httpClient, client.URL())
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// warmUp sends a HEAD request to the root of the host of target, leaving the
+// connection in the pool of httpClient. Any response means the connection
+// is valid.
+func warmUp(ctx context.Context, httpClient *http.Client, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return errors.New("sentry URL has no host")
+	}
+	req, err := http.NewRequest(http.MethodHead, u.Scheme+"://"+u.Host+"/", nil)
+	if err != nil {
+		return err
+	}
+	res, err := httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// transportHTTPClient returns the HTTP client of the raven transport wrapped
+// by transport, or nil.
+func transportHTTPClient(transport raven.Transport) *http.Client {
+	switch t := transport.(type) {
+	case *raven.HTTPTransport:
+		return t.Client
+	case *batchTransport:
+		return t.httpClient
+	case *spoolTransport:
+		return transportHTTPClient(t.Transport)
+	case *statsTransport:
+		return transportHTTPClient(t.Transport)
+	case *compatTransport:
+		return transportHTTPClient(t.Transport)
+	case *attachmentTransport:
+		return transportHTTPClient(t.Transport)
+	}
+	return nil
+}
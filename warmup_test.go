@@ -0,0 +1,66 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmUp(t *testing.T) {
+	a := assert.New(t)
+
+	var conns, heads, posts int32
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodHead:
+			atomic.AddInt32(&heads, 1)
+		case http.MethodPost:
+			atomic.AddInt32(&posts, 1)
+		}
+	}))
+	s.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.Start()
+	defer s.Close()
+	dsn := fmt.Sprintf("http://public:secret@%s/sentry/project-id", strings.TrimPrefix(s.URL, "http://"))
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	hook.Timeout = time.Second
+
+	a.NoError(hook.WarmUp(time.Second))
+	a.Equal(int32(1), atomic.LoadInt32(&heads))
+	a.Equal(int32(1), atomic.LoadInt32(&conns))
+
+	logger := getTestLogger()
+	logger.Hooks.Add(hook)
+	logger.Error(message)
+	a.Equal(int32(1), atomic.LoadInt32(&posts))
+	a.Equal(int32(1), atomic.LoadInt32(&conns), "the event should reuse the warmed up connection")
+}
+
+func TestWarmUpFailure(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(http.ResponseWriter, *http.Request) {})
+	s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	a.Error(hook.WarmUp(100*time.Millisecond), "an unreachable server should be an error")
+}
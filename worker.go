@@ -0,0 +1,54 @@
+package logrus_sentry
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	tagWorker      = "worker"
+	tagWorkerIndex = "worker_index"
+	tagWorkerStart = "worker_start"
+
+	extraWorkerDuration = "worker_duration"
+)
+
+// Worker wraps fn so that the error it returns is reported to sentry, tagged
+// with the worker's name, index and start time, before being returned. It is
+// reported at the error level, if it is one of the hook's levels, and the
+// errors of the hook are passed to its error handlers.
+// It fits errgroup.Group.Go and any other func() error based pool:
+//
+//	for i, url := range urls {
+//		g.Go(hook.Worker("fetch", i, func() error { return fetch(url) }))
+//	}
+func (hook *SentryHook) Worker(name string, index int, fn func() error) func() error {
+	return func() error {
+		start := time.Now()
+		err := fn()
+		if err == nil || !hook.hasLevel(logrus.ErrorLevel) {
+			return err
+		}
+
+		entry := &logrus.Entry{
+			Data: logrus.Fields{
+				logrus.ErrorKey: err,
+				fieldTags: raven.Tags{
+					{Key: tagWorker, Value: name},
+					{Key: tagWorkerIndex, Value: strconv.Itoa(index)},
+					{Key: tagWorkerStart, Value: start.Format(time.RFC3339Nano)},
+				},
+				extraWorkerDuration: time.Since(start).String(),
+			},
+			Time:    time.Now(),
+			Level:   logrus.ErrorLevel,
+			Message: fmt.Sprintf("worker %s #%d failed: %v", name, index, err),
+		}
+		hook.fireAndReport(entry)
+		return err
+	}
+}
@@ -0,0 +1,59 @@
+package logrus_sentry
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/musqdp/raven-go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorker(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		a.NoError(hook.Worker("fetch", 1, func() error { return nil })())
+
+		workerErr := errors.New("fetch failed")
+		err = hook.Worker("fetch", 3, func() error { return workerErr })()
+		a.Equal(workerErr, err, "worker error must be propagated")
+
+		packet := <-pch
+		a.Equal("worker fetch #3 failed: fetch failed", packet.Message)
+		a.Equal("fetch failed", packet.Culprit)
+		a.Contains(packet.Tags, raven.Tag{Key: "worker", Value: "fetch"})
+		a.Contains(packet.Tags, raven.Tag{Key: "worker_index", Value: "3"})
+		var hasStart bool
+		for _, tag := range packet.Tags {
+			hasStart = hasStart || tag.Key == "worker_start"
+		}
+		a.True(hasStart, "worker_start tag must be set")
+		a.Contains(packet.Extra, "worker_duration")
+	})
+}
+
+func TestWorkerLevels(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.WarnLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		workerErr := errors.New("fetch failed")
+		a.Equal(workerErr, hook.Worker("fetch", 1, func() error { return workerErr })())
+		select {
+		case <-pch:
+			t.Error("the error level is not one of the hook's levels")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
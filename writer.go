@@ -0,0 +1,54 @@
+package logrus_sentry
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// hookWriter reports every line written to it.
+type hookWriter struct {
+	hook  *SentryHook
+	level logrus.Level
+}
+
+// Writer returns an io.Writer reporting every write as an event of the given
+// level, to be used with the standard library logger:
+//
+//	log.SetOutput(io.MultiWriter(os.Stderr, hook.Writer(logrus.ErrorLevel)))
+//
+// The events go through the same pipeline as logrus entries. Nothing is
+// reported if the level is not one of the hook's levels. Writes do not fail:
+// the errors of the hook, e.g. send timeouts, are passed to its error
+// handlers.
+func (hook *SentryHook) Writer(level logrus.Level) io.Writer {
+	return &hookWriter{hook: hook, level: level}
+}
+
+func (w *hookWriter) Write(p []byte) (int, error) {
+	if !w.hook.hasLevel(w.level) {
+		return len(p), nil
+	}
+	// the log package writes one message per call
+	msg := strings.TrimSuffix(string(p), "\n")
+	entry := &logrus.Entry{
+		Data:    logrus.Fields{},
+		Time:    time.Now(),
+		Level:   w.level,
+		Message: msg,
+	}
+	w.hook.fireAndReport(entry)
+	return len(p), nil
+}
+
+// hasLevel reports whether level is one of the hook's levels.
+func (hook *SentryHook) hasLevel(level logrus.Level) bool {
+	for _, l := range hook.levels {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package logrus_sentry
+
+import (
+	"log"
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	a := assert.New(t)
+
+	WithTestDSN(t, func(dsn string, pch <-chan *resultPacket) {
+		hook, err := NewSentryHook(dsn, []logrus.Level{
+			logrus.ErrorLevel,
+		})
+		a.NoError(err, "NewSentryHook should be NoError")
+
+		log.New(hook.Writer(logrus.InfoLevel), "", 0).Print("ignored")
+		log.New(hook.Writer(logrus.ErrorLevel), "app: ", 0).Print(message)
+
+		packet := <-pch
+		a.Equal("app: "+message, packet.Message)
+		a.Equal("error", string(packet.Level))
+	})
+}
+
+func TestWriterFailure(t *testing.T) {
+	a := assert.New(t)
+
+	s, dsn := httptestNewServer(func(rw http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		rw.WriteHeader(http.StatusBadRequest)
+	})
+	defer s.Close()
+
+	hook, err := NewSentryHook(dsn, []logrus.Level{
+		logrus.ErrorLevel,
+	})
+	a.NoError(err, "NewSentryHook should be NoError")
+	var errs []error
+	hook.AddErrorHandler(func(entry *logrus.Entry, err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	})
+
+	n, err := hook.Writer(logrus.ErrorLevel).Write([]byte(message + "\n"))
+	a.NoError(err, "the line should be accepted")
+	a.Equal(len(message)+1, n)
+	a.Len(errs, 1, "the send error should be passed to the error handlers once")
+}